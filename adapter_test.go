@@ -0,0 +1,63 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+
+	_, ctx := factory.NewBuilder(context.Background()).Name("auth").Field("tenant", "t1").Branch()
+	log := slog.New(NewSlogHandler(factory)).With("user_id", 42).WithGroup("login")
+	log.InfoContext(ctx, "logged in", slog.Group("req", "ip", "127.0.0.1"))
+	log.DebugContext(ctx, "filtered")
+
+	out := buf.String()
+	for _, want := range []string{"msg=\"logged in\"", "wlog.fp=/auth/login", "tenant=t1", "user_id=42", "req.ip=127.0.0.1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("slog record should contain %q, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "filtered") {
+		t.Errorf("debug record should be filtered, got %q", out)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+
+	std := NewStdLogger(factory, "lib")
+	std.Printf("[ERROR] connection reset")
+	std.Printf("warn: retrying")
+	std.Printf("plain line")
+	std.Printf("information is not a level")
+
+	out := buf.String()
+	for _, want := range []string{
+		"level=error msg=\"connection reset\"",
+		"level=warning msg=retrying",
+		"level=info msg=\"plain line\"",
+		"level=info msg=\"information is not a level\"",
+		"wlog.fp=/lib",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("std log should contain %q, got %q", want, out)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package wlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// AdminHandler is a http.Handler to inspect and change the levels of the registered factories at runtime
+	// GET lists the factories, PUT or POST applies an AdminChange sent as JSON
+	AdminHandler struct {
+		mu      sync.Mutex
+		reverts map[adminTarget]*adminRevert
+	}
+
+	// AdminChange changes the level of a factory, or the level override of a chain pattern when Chain is set
+	// an empty Level removes the override of the chain, and a TTL like "10m" reverts the change after it
+	AdminChange struct {
+		Factory string `json:"factory"`
+		Chain   string `json:"chain,omitempty"`
+		Level   string `json:"level,omitempty"`
+		TTL     string `json:"ttl,omitempty"`
+	}
+
+	// AdminFactory describes a registered factory
+	AdminFactory struct {
+		Name string `json:"name"`
+		// Level is nil for factories built from an EntryMaker
+		Level       *logrus.Level           `json:"level,omitempty"`
+		ChainLevels map[string]logrus.Level `json:"chain_levels"`
+		Sinks       []SinkStats             `json:"sinks"`
+	}
+
+	adminTarget struct {
+		factory string
+		chain   string
+	}
+
+	// adminRevert restores a target when its timer fires, unless the target changed since
+	adminRevert struct {
+		timer    *time.Timer
+		restore  adminState
+		expected adminState
+	}
+
+	// adminState is the level of a target, a chain pattern may have none
+	adminState struct {
+		level logrus.Level
+		set   bool
+	}
+)
+
+// NewAdminHandler creates an AdminHandler
+func NewAdminHandler() *AdminHandler {
+	return &AdminHandler{reverts: make(map[adminTarget]*adminRevert)}
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		var list []AdminFactory
+		Range(func(name string, f *Factory) bool {
+			list = append(list, describeFactory(name, f))
+			return true
+		})
+		writeAdminJSON(w, http.StatusOK, list)
+	case http.MethodPut, http.MethodPost:
+		var change AdminChange
+		if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+			http.Error(w, "invalid change: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		f, ok := Get(change.Factory)
+		if !ok {
+			http.Error(w, "factory not found: "+change.Factory, http.StatusNotFound)
+			return
+		}
+		if err := h.apply(f, change); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, describeFactory(change.Factory, f))
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// apply applies the change to the factory, and schedules its revert when it has a TTL
+// a change replacing a pending one keeps the state from before the pending one to revert to
+func (h *AdminHandler) apply(f *Factory, change AdminChange) error {
+	ttl, err := parseDuration(change.TTL)
+	if err != nil {
+		return err
+	}
+	pattern := ParseChain(change.Chain)
+	target := adminTarget{factory: change.Factory, chain: pattern.String()}
+
+	var set func()
+	switch {
+	case len(pattern) > 0 && change.Level == "":
+		set = func() { f.UnsetChainLevel(target.chain) }
+	case len(pattern) > 0:
+		level, err := parseLevel(change.Level, logrus.InfoLevel)
+		if err != nil {
+			return err
+		}
+		set = func() { f.SetChainLevel(target.chain, level) }
+	case change.Level == "":
+		return ErrLevelRequired
+	case loggerOf(f) == nil:
+		return ErrNoLogger
+	default:
+		level, err := parseLevel(change.Level, logrus.InfoLevel)
+		if err != nil {
+			return err
+		}
+		set = func() { f.SetLevel(level) }
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	restore := stateOf(f, target)
+	if pending, ok := h.reverts[target]; ok {
+		pending.timer.Stop()
+		restore = pending.restore
+		delete(h.reverts, target)
+	}
+	set()
+	LInit.Log("admin").WithFields(Fields{
+		"factory": change.Factory, "chain": target.chain, "level": change.Level, "ttl": change.TTL,
+	}).Info("wlog level changed")
+
+	if ttl > 0 {
+		revert := &adminRevert{restore: restore, expected: stateOf(f, target)}
+		revert.timer = time.AfterFunc(ttl, func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if h.reverts[target] != revert {
+				return // replaced by a later change
+			}
+			delete(h.reverts, target)
+			log := LInit.Log("admin").WithFields(Fields{"factory": target.factory, "chain": target.chain})
+			// a reload or SetLevel since the change wins over the state from before it
+			if stateOf(f, target) != revert.expected {
+				log.Info("wlog level change expired, kept the level set since")
+				return
+			}
+			restoreTo(f, target, revert.restore)
+			log.Info("wlog level change expired")
+		})
+		h.reverts[target] = revert
+	}
+	return nil
+}
+
+// stateOf returns the current level of the target
+func stateOf(f *Factory, target adminTarget) adminState {
+	if target.chain == Chain(nil).String() {
+		return adminState{level: loggerOf(f).GetLevel(), set: true}
+	}
+	level, ok := f.ChainLevels()[target.chain]
+	return adminState{level: level, set: ok}
+}
+
+// restoreTo sets the target back to the state
+func restoreTo(f *Factory, target adminTarget, state adminState) {
+	switch {
+	case target.chain == Chain(nil).String():
+		f.SetLevel(state.level)
+	case state.set:
+		f.SetChainLevel(target.chain, state.level)
+	default:
+		f.UnsetChainLevel(target.chain)
+	}
+}
+
+func describeFactory(name string, f *Factory) AdminFactory {
+	described := AdminFactory{Name: name, ChainLevels: f.ChainLevels(), Sinks: f.Sinks()}
+	if l := loggerOf(f); l != nil {
+		level := l.GetLevel()
+		described.Level = &level
+	}
+	return described
+}
+
+// loggerOf returns the logger of the factory, nil when it is built from an EntryMaker
+func loggerOf(f *Factory) *logrus.Logger {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.defaultEntry == nil {
+		return nil
+	}
+	return f.defaultEntry.Logger
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,101 @@
+package wlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestAdminHandler(t *testing.T) {
+	factory, err := NewFactory(createDiscardLogger())
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	if err = Register("admin-test", factory); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	defer Unregister("admin-test")
+	if err = Register("admin-test", factory); err != ErrFactoryExists {
+		t.Errorf("registering a taken name should fail with ErrFactoryExists, got %v", err)
+	}
+
+	handler := NewAdminHandler()
+	send := func(method, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := send(http.MethodPut, `{"factory": "admin-test", "level": "warn"}`); rec.Code != http.StatusOK {
+		t.Fatalf("change level failed: %d %s", rec.Code, rec.Body)
+	}
+	if factory.Logger().GetLevel() != logrus.WarnLevel {
+		t.Errorf("level should be warn, got %s", factory.Logger().GetLevel())
+	}
+
+	rec := send(http.MethodPost, `{"factory": "admin-test", "chain": "/checkout", "level": "debug", "ttl": "20ms"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("change chain level failed: %d %s", rec.Code, rec.Body)
+	}
+	if levels := factory.ChainLevels(); levels["/checkout"] != logrus.DebugLevel {
+		t.Errorf("chain level should be debug, got %v", levels)
+	}
+
+	var listed []AdminFactory
+	if err = json.NewDecoder(send(http.MethodGet, "").Body).Decode(&listed); err != nil {
+		t.Fatalf("decode list failed: %v", err)
+	}
+	found := false
+	for _, described := range listed {
+		if described.Name == "admin-test" {
+			found = true
+			if described.Level == nil || *described.Level != logrus.WarnLevel || described.ChainLevels["/checkout"] != logrus.DebugLevel {
+				t.Errorf("factory should be listed with its levels, got %+v", described)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("registered factory should be listed, got %+v", listed)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(factory.ChainLevels()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if levels := factory.ChainLevels(); len(levels) != 0 {
+		t.Errorf("chain level should be reverted after the ttl, got %v", levels)
+	}
+
+	// a level set since the change, e.g. by a reload, is not reverted to the stale one
+	if rec = send(http.MethodPut, `{"factory": "admin-test", "level": "debug", "ttl": "20ms"}`); rec.Code != http.StatusOK {
+		t.Fatalf("change level failed: %d %s", rec.Code, rec.Body)
+	}
+	factory.SetLevel(logrus.ErrorLevel)
+	pending := func() int {
+		handler.mu.Lock()
+		defer handler.mu.Unlock()
+		return len(handler.reverts)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for pending() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if level := factory.Logger().GetLevel(); pending() > 0 || level != logrus.ErrorLevel {
+		t.Errorf("the level set since the change should be kept, got %s", level)
+	}
+
+	if rec = send(http.MethodPut, `{"factory": "absent", "level": "debug"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown factory should be 404, got %d", rec.Code)
+	}
+	if rec = send(http.MethodPut, `{"factory": "admin-test", "level": "loud"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid level should be 400, got %d", rec.Code)
+	}
+	if rec = send(http.MethodDelete, ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("delete should be 405, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,239 @@
+package wlog
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultAsyncQueueSize is the queue size of AsyncWriter when QueueSize is not given
+const defaultAsyncQueueSize = 1024
+
+// AsyncPolicy decides what an AsyncWriter does when its queue is full
+type AsyncPolicy int
+
+const (
+	// AsyncBlock waits for room in the queue
+	AsyncBlock AsyncPolicy = 0
+
+	// AsyncDropNewest drops what is being written
+	AsyncDropNewest AsyncPolicy = 1
+
+	// AsyncDropOldest drops the oldest queued entry to make room
+	AsyncDropOldest AsyncPolicy = 2
+
+	// AsyncDropBelowLevel drops entries less severe than AsyncOptions.Level, and waits for the others
+	AsyncDropBelowLevel AsyncPolicy = 3
+)
+
+type (
+	// LevelWriter is an io.Writer which is also told the level of what is written
+	// followers of a Factory use WriteLevel when their output implements it
+	LevelWriter interface {
+		io.Writer
+		WriteLevel(level logrus.Level, p []byte) (int, error)
+	}
+
+	// AsyncOptions configures an AsyncWriter
+	AsyncOptions struct {
+		// QueueSize is the number of entries which can wait to be written, 1024 by default
+		QueueSize int
+		// Policy decides what to do when the queue is full
+		Policy AsyncPolicy
+		// Level is the least severe level kept when the queue is full, under AsyncDropBelowLevel
+		Level logrus.Level
+	}
+
+	// AsyncWriter writes to the underlying writer in background, through a bounded queue
+	// so that logging never stalls on a slow output, unless the policy says so
+	AsyncWriter struct {
+		out  io.Writer
+		opts AsyncOptions
+
+		queue   chan asyncRecord
+		mu      sync.RWMutex // guards closed against the senders of queue
+		closed  bool
+		done    chan struct{}
+		dropped atomic.Uint64
+	}
+
+	asyncRecord struct {
+		p     []byte
+		level logrus.Level
+		flush chan struct{} // not nil for flush markers
+	}
+)
+
+var _ LevelWriter = (*AsyncWriter)(nil)
+
+// NewAsyncWriter creates an AsyncWriter writing to out, and starts its background writer
+func NewAsyncWriter(out io.Writer, opts AsyncOptions) *AsyncWriter {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultAsyncQueueSize
+	}
+	w := &AsyncWriter{
+		out:   out,
+		opts:  opts,
+		queue: make(chan asyncRecord, opts.QueueSize),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// SetAsync makes the output of the Factory instance asynchronous, and returns the AsyncWriter
+// entries keep their level on the way, which is what AsyncDropBelowLevel works on
+// it only wraps the output of the logger, which sinks replace, see Sink.Out for asynchronous sinks
+// it returns ErrNoLogger when the entries of the factory are made by an EntryMaker
+func (f *Factory) SetAsync(opts AsyncOptions) (*AsyncWriter, error) {
+	logger := loggerOf(f)
+	if logger == nil {
+		return nil, ErrNoLogger
+	}
+	w := NewAsyncWriter(logger.Out, opts)
+	logger.SetOutput(w)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.async = w
+	return w, nil
+}
+
+// Write queues p, entries without level are kept as if they were at AsyncOptions.Level
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(w.opts.Level, p)
+}
+
+// WriteLevel queues p of the level, p is copied since logrus recycles it
+func (w *AsyncWriter) WriteLevel(level logrus.Level, p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+
+	rec := asyncRecord{p: append([]byte(nil), p...), level: level}
+	select {
+	case w.queue <- rec:
+		return len(p), nil
+	default:
+	}
+
+	switch w.opts.Policy {
+	case AsyncDropNewest:
+		w.dropped.Add(1)
+	case AsyncDropOldest:
+		w.dropOldest(rec)
+	case AsyncDropBelowLevel:
+		if level > w.opts.Level {
+			w.dropped.Add(1)
+			break
+		}
+		w.queue <- rec
+	default:
+		w.queue <- rec
+	}
+	// dropped entries are reported by Dropped, not as write errors
+	return len(p), nil
+}
+
+// dropOldest makes room for rec by dropping the oldest entries, without ever waiting for the queue
+// flush markers are never dropped, they are held and put back behind, ahead of rec
+func (w *AsyncWriter) dropOldest(rec asyncRecord) {
+	var markers []asyncRecord
+	for {
+		for len(markers) > 0 {
+			select {
+			case w.queue <- markers[0]:
+				markers = markers[1:]
+				continue
+			default:
+			}
+			break
+		}
+		if len(markers) == 0 {
+			select {
+			case w.queue <- rec:
+				return
+			default:
+			}
+		}
+		select {
+		case old := <-w.queue:
+			if old.flush != nil {
+				markers = append(markers, old)
+				continue
+			}
+			w.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// Flush waits until everything queued before it is written, or ctx is done
+// the underlying writer is synced when it supports Sync
+func (w *AsyncWriter) Flush(ctx context.Context) error {
+	marker := make(chan struct{})
+
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return nil
+	}
+	select {
+	case w.queue <- asyncRecord{flush: marker}:
+		w.mu.RUnlock()
+	case <-ctx.Done():
+		w.mu.RUnlock()
+		return ctx.Err()
+	}
+
+	select {
+	case <-marker:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes what is queued, and closes the underlying writer unless it is stdout or stderr
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+	return closeOutput(w.out)
+}
+
+// Dropped returns the number of entries dropped since the writer was created
+func (w *AsyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Len returns the number of entries waiting in the queue
+func (w *AsyncWriter) Len() int {
+	return len(w.queue)
+}
+
+// run writes the queued records until the queue is closed
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for rec := range w.queue {
+		if rec.flush != nil {
+			syncOutput(w.out)
+			close(rec.flush)
+			continue
+		}
+		_, _ = w.out.Write(rec.p)
+	}
+	syncOutput(w.out)
+}
@@ -0,0 +1,218 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// gateWriter blocks writing until it is opened
+type gateWriter struct {
+	open chan struct{}
+	mu   sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	<-w.open
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncDropBelowLevel(t *testing.T) {
+	out := &gateWriter{open: make(chan struct{})}
+	logger := createDiscardLogger()
+	logger.SetOutput(out)
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	async, err := factory.SetAsync(AsyncOptions{QueueSize: 2, Policy: AsyncDropBelowLevel, Level: logrus.WarnLevel})
+	if err != nil {
+		t.Fatalf("set async failed: %v", err)
+	}
+
+	log := factory.NewBuilder(context.Background()).Name("hot").Leaf()
+	// the first entry is taken by the background writer, and two more fill the queue
+	for i := 0; i < 6; i++ {
+		log.Info("info")
+	}
+	if async.Dropped() == 0 {
+		t.Error("info entries should be dropped when the queue is full")
+	}
+
+	errorLogged := make(chan struct{})
+	go func() {
+		log.Error("error")
+		close(errorLogged)
+	}()
+	close(out.open)
+	<-errorLogged
+
+	if err = async.Flush(context.Background()); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "msg=error") {
+		t.Errorf("error entries should wait for room in the queue, got %q", out.String())
+	}
+	if err = async.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if _, err = async.Write([]byte("late")); err != ErrWriterClosed {
+		t.Errorf("write after close should fail, got %v", err)
+	}
+}
+
+func TestAsyncPolicies(t *testing.T) {
+	// waitLen waits for the background writer to take what it can
+	waitLen := func(w *AsyncWriter, n int) {
+		deadline := time.Now().Add(time.Second)
+		for w.Len() != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("expect %d queued, got %d", n, w.Len())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// start writes the first line, which the background writer takes and blocks on
+	start := func(policy AsyncPolicy) (*AsyncWriter, *gateWriter) {
+		out := &gateWriter{open: make(chan struct{})}
+		w := NewAsyncWriter(out, AsyncOptions{QueueSize: 2, Policy: policy})
+		_, _ = w.Write([]byte("0;"))
+		waitLen(w, 0)
+		return w, out
+	}
+	finish := func(w *AsyncWriter, out *gateWriter) string {
+		if err := w.Close(); err != nil {
+			t.Fatalf("close failed: %v", err)
+		}
+		return out.String()
+	}
+
+	t.Run("block", func(t *testing.T) {
+		w, out := start(AsyncBlock)
+		_, _ = w.Write([]byte("1;"))
+		_, _ = w.Write([]byte("2;"))
+		written := make(chan struct{})
+		go func() {
+			_, _ = w.Write([]byte("3;"))
+			close(written)
+		}()
+		select {
+		case <-written:
+			t.Error("writing to a full queue should wait")
+		case <-time.After(20 * time.Millisecond):
+		}
+		close(out.open)
+		<-written
+		if got := finish(w, out); got != "0;1;2;3;" || w.Dropped() != 0 {
+			t.Errorf("nothing should be dropped, got %q, dropped %d", got, w.Dropped())
+		}
+	})
+
+	t.Run("drop newest", func(t *testing.T) {
+		w, out := start(AsyncDropNewest)
+		for _, p := range []string{"1;", "2;", "3;", "4;"} {
+			_, _ = w.Write([]byte(p))
+		}
+		close(out.open)
+		if got := finish(w, out); got != "0;1;2;" || w.Dropped() != 2 {
+			t.Errorf("the newest entries should be dropped, got %q, dropped %d", got, w.Dropped())
+		}
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		w, out := start(AsyncDropOldest)
+		for _, p := range []string{"1;", "2;", "3;", "4;"} {
+			_, _ = w.Write([]byte(p))
+		}
+		close(out.open)
+		if got := finish(w, out); got != "0;3;4;" || w.Dropped() != 2 {
+			t.Errorf("the oldest entries should be dropped, got %q, dropped %d", got, w.Dropped())
+		}
+	})
+
+	t.Run("drop oldest keeps flush markers", func(t *testing.T) {
+		w, out := start(AsyncDropOldest)
+		_, _ = w.Write([]byte("1;"))
+		flushed := make(chan error)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			flushed <- w.Flush(ctx)
+		}()
+		waitLen(w, 2)
+		// the queue is full and holds the marker, writing neither waits nor drops the marker
+		_, _ = w.Write([]byte("2;"))
+		_, _ = w.Write([]byte("3;"))
+		close(out.open)
+		if err := <-flushed; err != nil {
+			t.Errorf("flush should complete, got %v", err)
+		}
+		if got := finish(w, out); got != "0;3;" || w.Dropped() != 2 {
+			t.Errorf("the oldest entries should be dropped, got %q, dropped %d", got, w.Dropped())
+		}
+	})
+}
+
+func TestAsyncSink(t *testing.T) {
+	loggerOut := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(loggerOut)
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	async, err := factory.SetAsync(AsyncOptions{})
+	if err != nil {
+		t.Fatalf("set async failed: %v", err)
+	}
+
+	// sinks replace the output of the logger, and with it the AsyncWriter of SetAsync
+	sinkOut := &gateWriter{open: make(chan struct{})}
+	if err = factory.AddSink(Sink{Name: "audit", Out: NewAsyncWriter(sinkOut, AsyncOptions{}), Level: logrus.InfoLevel}); err != nil {
+		t.Fatalf("add sink failed: %v", err)
+	}
+
+	// the gate is closed, only an asynchronous sink lets the entry return
+	factory.NewBuilder(context.Background()).Name("audit").Leaf().Info("sunk")
+	close(sinkOut.open)
+	if err = factory.Flush(context.Background()); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	if !strings.Contains(sinkOut.String(), "msg=sunk") {
+		t.Errorf("flush should wait for the asynchronous sink, got %q", sinkOut.String())
+	}
+	if async.Len() != 0 || loggerOut.Len() != 0 {
+		t.Errorf("the output of the logger should not be written once there are sinks, got %q", loggerOut.String())
+	}
+	if err = factory.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+}
+
+func TestAsyncEntryMaker(t *testing.T) {
+	logger := createDiscardLogger()
+	factory, err := NewFactory(EntryMaker(func(ctx context.Context) *logrus.Entry {
+		return logrus.NewEntry(logger).WithContext(ctx)
+	}))
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	if async, err := factory.SetAsync(AsyncOptions{}); err != ErrNoLogger || async != nil {
+		t.Errorf("factories of EntryMaker have no output to wrap, got %v, %v", async, err)
+	}
+}
@@ -46,13 +46,19 @@ func (b *Builder) Name(chainNodes ...string) *Builder {
 
 // Field adds a single field to the builder
 func (b *Builder) Field(key string, value any) *Builder {
-	b.columns.Set(Column{Key: key, Value: value})
+	b.columns = b.columns.Set(Column{Key: key, Value: value})
+	return b
+}
+
+// Columns adds columns to the builder, e.g. the typed columns created by Int, Str...
+func (b *Builder) Columns(cols ...Column) *Builder {
+	b.columns = b.columns.Set(cols...)
 	return b
 }
 
 // Fields adds multiple columns to the builder
 func (b *Builder) Fields(fields Fields) *Builder {
-	b.columns.Set(ColumnsFromFields(fields)...)
+	b.columns = b.columns.Set(ColumnsFromFields(fields)...)
 	return b
 }
 
@@ -82,12 +88,14 @@ func (b *Builder) Build() (WLog, context.Context) {
 		// merge entry and ctx
 		chainForEntry = chainInCtx.Join(b.chainNode)
 		columnsForEntry = columnsInCtx.Combine(b.columns)
+		columnsForEntry.box()
 		newCtx = chainForEntry.WriteCtx(b.ctx)
 		newCtx = columnsForEntry.WriteCtx(newCtx)
 	case NewTree:
 		// only use new chain and columns
 		chainForEntry = b.chainNode
-		columnsForEntry = b.columns
+		columnsForEntry = Columns(nil).Combine(b.columns) // copy, b.columns is recycled with the builder
+		columnsForEntry.box()
 		newCtx = chainForEntry.WriteCtx(b.ctx)
 		newCtx = columnsForEntry.WriteCtx(newCtx)
 	default: // default strategy is ForkLeaf
@@ -101,6 +109,16 @@ func (b *Builder) Build() (WLog, context.Context) {
 	// add fields and fingerprints to entry
 	entry = columnsForEntry.WriteEntry(entry)
 	entry = chainForEntry.WriteEntry(entry)
+	// add trace context to entry, the span of a new node goes into ctx
+	if b.factory.Tracing() {
+		trace, cache := traceFor(b.ctx, b.strategy)
+		if cache {
+			newCtx = trace.WriteCtx(newCtx)
+		}
+		entry = trace.WriteEntry(entry)
+	}
+	// filter by the level of the chain, and apply emission stages
+	entry = b.factory.route(newCtx, entry, chainForEntry)
 
 	// make WLog instance
 	wlog := WLog{
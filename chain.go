@@ -31,6 +31,31 @@ func (cc Chain) String() string {
 	return builder.String()
 }
 
+// ParseChain parses the string representation of a chain, e.g. "/a/b/c"
+// it is the reverse of Chain.String, empty nodes are ignored
+func ParseChain(s string) Chain {
+	var chain Chain
+	for _, node := range strings.Split(s, "/") {
+		if node != "" {
+			chain = append(chain, node)
+		}
+	}
+	return chain
+}
+
+// HasPrefix reports whether the chain begins with prefix
+func (cc Chain) HasPrefix(prefix Chain) bool {
+	if len(prefix) > len(cc) {
+		return false
+	}
+	for i, node := range prefix {
+		if cc[i] != node {
+			return false
+		}
+	}
+	return true
+}
+
 // Join with the given fingerprints
 func (cc Chain) Join(appends Chain) Chain {
 	if nil == cc {
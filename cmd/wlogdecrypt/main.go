@@ -0,0 +1,52 @@
+// Command wlogdecrypt reads wlog output from stdin, and writes it to stdout with the values
+// encrypted by wlog.Encryption decrypted
+//
+//	WLOG_KEYS="k1=<base64 key>,k2=<base64 key>" wlogdecrypt < app.log
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/khicago/wlog"
+)
+
+func main() {
+	spec := flag.String("keys", os.Getenv("WLOG_KEYS"), "keys as id=base64,..., WLOG_KEYS by default")
+	flag.Parse()
+
+	keys, err := parseKeys(*spec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wlogdecrypt:", err)
+		os.Exit(2)
+	}
+	if err = wlog.Decrypt(os.Stdin, os.Stdout, keys); err != nil {
+		fmt.Fprintln(os.Stderr, "wlogdecrypt:", err)
+		os.Exit(1)
+	}
+}
+
+func parseKeys(spec string) (wlog.StaticKeys, error) {
+	keys := wlog.StaticKeys{Keys: make(map[string][]byte)}
+	for _, pair := range strings.Split(spec, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(pair, "=")
+		if !ok {
+			return keys, fmt.Errorf("%q is not id=base64", pair)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return keys, fmt.Errorf("key %s: %w", id, err)
+		}
+		keys.Keys[id] = key
+	}
+	if len(keys.Keys) == 0 {
+		return keys, fmt.Errorf("no keys given, see -keys")
+	}
+	return keys, nil
+}
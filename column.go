@@ -9,9 +9,16 @@ import (
 
 type (
 	// Column represents a key-value pair
+	// the columns created by the typed constructors, e.g. Int, keep their value unboxed, Value is nil for them
+	// except for Time, where it holds the location; read the value of any column by Any
+	// the unboxed value makes a Column 64 bytes instead of 32
 	Column struct {
 		Key   string
 		Value any
+
+		kind columnKind
+		num  uint64
+		str  string
 	}
 
 	// Columns is a slice of Column
@@ -34,15 +41,26 @@ func (c Columns) WriteCtx(ctx context.Context) context.Context {
 	return context.WithValue(ctx, CtxKeyColumns, c)
 }
 
-// Combine merge current columns with new columns
+// Combine merge current columns with new columns into a new slice
+// neither of them is modified, since they might be cached in context or recycled
 func (c Columns) Combine(other Columns) Columns {
-	return c.Set(other...)
+	combined := make(Columns, 0, len(c)+len(other))
+	combined = append(combined, c...)
+	for _, col := range other {
+		combined = combined.Set(col)
+	}
+	return combined
 }
 
 // ToFields convert columns to fields
+// the values of typed columns are referenced as *TypedValue, thus c must not be modified while the fields are used
 func (c Columns) ToFields() Fields {
 	fields := make(Fields, len(c))
-	for _, col := range c {
+	for i, col := range c {
+		if col.kind != kindAny {
+			fields[col.Key] = (*TypedValue)(&c[i])
+			continue
+		}
 		fields[col.Key] = col.Value
 	}
 	return fields
@@ -76,9 +94,9 @@ func (c Columns) Sorted() Columns {
 
 // Set add or update columns, keep order and unique, assume it's mostly ordered
 func (c Columns) Set(cols ...Column) Columns {
-	// if original slice is empty, return new columns directly
+	// if original slice is empty, take the new columns, the capacity of c is reused
 	if len(c) == 0 {
-		return cols
+		return append(c, cols...)
 	}
 
 	// mostly ordered and less items, try to modify in place
@@ -107,6 +125,10 @@ func (c Columns) Set(cols ...Column) Columns {
 func ColumnsFromFields(fields Fields) Columns {
 	columns := make([]Column, 0, len(fields))
 	for k, v := range fields {
+		if typed, ok := v.(*TypedValue); ok {
+			columns = append(columns, Column(*typed))
+			continue
+		}
 		columns = append(columns, Column{Key: k, Value: v})
 	}
 	return columns
@@ -0,0 +1,454 @@
+package wlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/khicago/irr"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// Config declares a Factory, it is usually loaded from JSON by LoadConfig
+	//
+	//	{
+	//	  "level": "info",
+	//	  "chain_levels": {"/payment/*": "debug"},
+	//	  "formatter": "json",
+	//	  "output": "/var/log/app.log",
+	//	  "rotate": {"max_size_mb": 100, "max_backups": 7, "compress": true},
+	//	  "sinks": [{"name": "errors", "output": "stderr", "formatter": "text", "level": "error"}],
+	//	  "sampling": {"first": 100, "thereafter": 100, "tick": "1s"},
+	//	  "tracing": true,
+	//	  "dev": false,
+	//	  "default": true,
+	//	  "name": "orders"
+	//	}
+	Config struct {
+		// Level of the factory, "info" by default
+		Level string `json:"level,omitempty"`
+		// ChainLevels maps chain patterns to levels, see Factory.SetChainLevel
+		ChainLevels map[string]string `json:"chain_levels,omitempty"`
+		// Formatter is one of "text" (default), "json", "tree" and "logrus-json"
+		Formatter string `json:"formatter,omitempty"`
+		// Output is "stderr" (default), "stdout", "discard" or a file path
+		Output string `json:"output,omitempty"`
+		// Rotate configures the rotation of a file Output
+		Rotate *RotateConfig `json:"rotate,omitempty"`
+		// Sinks replace Output when given, see Factory.AddSink
+		Sinks []SinkConfig `json:"sinks,omitempty"`
+		// Sampling is disabled when nil
+		Sampling *SamplingConfig `json:"sampling,omitempty"`
+		// Tracing enables trace_id and span_id, see Factory.SetTracing
+		Tracing bool `json:"tracing,omitempty"`
+		// Dev enables or disables the local dev methods, see DevEnabled, unchanged when nil
+		Dev *bool `json:"dev,omitempty"`
+		// Default installs the factory as the default one, which By, Leaf, Branch... log with
+		Default bool `json:"default,omitempty"`
+		// Name registers the factory under the name when given, see Register
+		Name string `json:"name,omitempty"`
+	}
+
+	// SinkConfig declares a Sink
+	SinkConfig struct {
+		Name        string        `json:"name"`
+		Output      string        `json:"output"`
+		Formatter   string        `json:"formatter,omitempty"`
+		Level       string        `json:"level,omitempty"`
+		ChainPrefix string        `json:"chain_prefix,omitempty"`
+		Rotate      *RotateConfig `json:"rotate,omitempty"`
+	}
+
+	// RotateConfig declares the RotateOptions of a file output
+	RotateConfig struct {
+		MaxSizeMB  int64  `json:"max_size_mb,omitempty"`
+		Interval   string `json:"interval,omitempty"`
+		MaxBackups int    `json:"max_backups,omitempty"`
+		Compress   bool   `json:"compress,omitempty"`
+	}
+
+	// SamplingConfig declares the Sampling
+	SamplingConfig struct {
+		First      int    `json:"first"`
+		Thereafter int    `json:"thereafter"`
+		Tick       string `json:"tick,omitempty"`
+	}
+)
+
+// Environment variables read by ConfigFromEnv
+const (
+	EnvLevel       = "WLOG_LEVEL"        // e.g. "info"
+	EnvChainLevels = "WLOG_CHAIN_LEVELS" // e.g. "/payment/*=debug,/poll=warn"
+	EnvFormatter   = "WLOG_FORMATTER"    // e.g. "json"
+	EnvOutput      = "WLOG_OUTPUT"       // e.g. "stdout" or a file path
+	EnvSampling    = "WLOG_SAMPLING"     // first/thereafter[/tick], e.g. "100/1000/1s"
+	EnvTracing     = "WLOG_TRACING"      // e.g. "true"
+	EnvDev         = "WLOG_DEV"          // e.g. "false"
+	EnvDefault     = "WLOG_DEFAULT"      // e.g. "true"
+	EnvName        = "WLOG_NAME"         // e.g. "orders"
+)
+
+// LoadConfig reads a JSON Config from r, and creates the Factory it declares
+// when the config says so, the factory is installed as the default one
+func LoadConfig(r io.Reader) (*Factory, error) {
+	cfg := &Config{}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, irr.Wrap(err, "failed to decode wlog config")
+	}
+	return cfg.Load()
+}
+
+// ConfigFromEnv reads a Config from the WLOG_* environment variables
+func ConfigFromEnv() (*Config, error) {
+	cfg := &Config{
+		Level:     os.Getenv(EnvLevel),
+		Formatter: os.Getenv(EnvFormatter),
+		Output:    os.Getenv(EnvOutput),
+		Name:      os.Getenv(EnvName),
+	}
+
+	if v := os.Getenv(EnvChainLevels); v != "" {
+		cfg.ChainLevels = make(map[string]string)
+		for _, pair := range strings.Split(v, ",") {
+			pattern, level, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				return nil, irr.Wrap(ErrInvalidConfig, "%s: %q is not pattern=level", EnvChainLevels, pair)
+			}
+			cfg.ChainLevels[pattern] = level
+		}
+	}
+
+	if v := os.Getenv(EnvSampling); v != "" {
+		parts := strings.Split(v, "/")
+		first, err1 := strconv.Atoi(parts[0])
+		thereafter, err2 := 0, error(nil)
+		if len(parts) > 1 {
+			thereafter, err2 = strconv.Atoi(parts[1])
+		}
+		if err := errors.Join(err1, err2); err != nil || len(parts) > 3 {
+			return nil, irr.Wrap(ErrInvalidConfig, "%s: %q is not first/thereafter[/tick]", EnvSampling, v)
+		}
+		cfg.Sampling = &SamplingConfig{First: first, Thereafter: thereafter}
+		if len(parts) == 3 {
+			cfg.Sampling.Tick = parts[2]
+		}
+	}
+
+	for _, b := range []struct {
+		env string
+		set func(bool)
+	}{
+		{EnvTracing, func(v bool) { cfg.Tracing = v }},
+		{EnvDev, func(v bool) { cfg.Dev = &v }},
+		{EnvDefault, func(v bool) { cfg.Default = v }},
+	} {
+		if v := os.Getenv(b.env); v != "" {
+			parsed, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, irr.Wrap(ErrInvalidConfig, "%s: %q is not a bool", b.env, v)
+			}
+			b.set(parsed)
+		}
+	}
+	return cfg, nil
+}
+
+// Load creates the Factory declared by the config, registers it under its name if given,
+// and installs it as the default one if declared
+func (c *Config) Load() (*Factory, error) {
+	f, err := c.NewFactory()
+	if err != nil {
+		return nil, err
+	}
+	if c.Name != "" {
+		if err = Register(c.Name, f); err != nil {
+			return nil, errors.Join(irr.Wrap(err, "failed to register factory %s", c.Name), f.Close(context.Background()))
+		}
+	}
+	if c.Default {
+		SetDefault(f)
+	}
+	return f, nil
+}
+
+// NewFactory creates the Factory declared by the config
+func (c *Config) NewFactory() (*Factory, error) {
+	formatter, err := newFormatter(c.Formatter)
+	if err != nil {
+		return nil, err
+	}
+	out, err := openOutput(c.Output, c.Rotate)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logrus.New()
+	logger.SetFormatter(formatter)
+	logger.SetOutput(out)
+	f, err := NewFactory(logger)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.Apply(f); err != nil {
+		_ = closeOutput(out)
+		return nil, err
+	}
+	return f, nil
+}
+
+// Apply sets the level, chain levels, sinks, sampling and tracing of the config to the factory,
+// and the enablement of the local dev methods; they replace what the factory had, at once
+// the sinks declared the same as the ones the factory has are kept open, and so is a sampler of the same sampling,
+// with what it has counted; the formatter and output of the config are only used when a factory is created
+func (c *Config) Apply(f *Factory) error {
+	return c.apply(f, nil)
+}
+
+// apply sets the config to the factory, only what changed since previous when it is given,
+// so that what was set at runtime, e.g. by SetChainLevel, outlives the settings the config did not change
+func (c *Config) apply(f *Factory, previous *Config) error {
+	level, err := parseLevel(c.Level, logrus.InfoLevel)
+	if err != nil {
+		return err
+	}
+
+	chainLevels := make(map[string]logrus.Level, len(c.ChainLevels))
+	for pattern, name := range c.ChainLevels {
+		if chainLevels[pattern], err = parseLevel(name, logrus.InfoLevel); err != nil {
+			return err
+		}
+	}
+
+	var sampling *Sampling
+	if c.Sampling != nil {
+		tick, err := parseDuration(c.Sampling.Tick)
+		if err != nil {
+			return err
+		}
+		if tick <= 0 {
+			tick = defaultSamplingTick
+		}
+		sampling = &Sampling{First: c.Sampling.First, Thereafter: c.Sampling.Thereafter, Tick: tick}
+	}
+
+	// f.mu is held from here on, so that the sinks planned are the current ones
+	current, next, err := c.lockSinks(f, previous)
+	if err != nil {
+		return err
+	}
+	if f.defaultEntry != nil && (previous == nil || previous.Level != c.Level) {
+		f.defaultEntry.Logger.SetLevel(level)
+	}
+
+	if previous == nil {
+		f.chainLevels = nil
+	} else {
+		for pattern := range previous.ChainLevels {
+			if _, ok := c.ChainLevels[pattern]; !ok {
+				f.chainLevels = f.chainLevels.unset(ParseChain(pattern).String())
+			}
+		}
+	}
+	for pattern, chainLevel := range chainLevels {
+		if previous == nil || previous.ChainLevels[pattern] != c.ChainLevels[pattern] {
+			f.chainLevels = f.chainLevels.set(newChainLevel(pattern, chainLevel))
+		}
+	}
+
+	var started, retired *sampler
+	unchanged := previous != nil && describe(previous.Sampling) == describe(c.Sampling)
+	same := (sampling == nil && f.sampler == nil) || (sampling != nil && f.sampler != nil && f.sampler.Sampling == *sampling)
+	if !unchanged && !same {
+		if sampling != nil {
+			started = newSampler(*sampling)
+		}
+		retired, f.sampler = f.sampler, started
+	}
+
+	if previous == nil || previous.Tracing != c.Tracing {
+		f.tracing = c.Tracing
+	}
+	f.sinks = next
+	f.mu.Unlock()
+
+	if started != nil {
+		started.start(f)
+	}
+	f.retireSampler(retired)
+
+	if c.Dev != nil && (previous == nil || describe(previous.Dev) != describe(c.Dev)) {
+		DevEnabled.Store(*c.Dev)
+	}
+	return current.without(next).close()
+}
+
+// lockSinks plans the sinks of the factory, and returns them with f.mu locked,
+// the plan is made again when the sinks change while it is made, e.g. by AddSink
+func (c *Config) lockSinks(f *Factory, previous *Config) (current, next sinks, err error) {
+	f.mu.RLock()
+	current = f.sinks
+	f.mu.RUnlock()
+	for {
+		if next, err = c.planSinks(current, previous); err != nil {
+			return nil, nil, err
+		}
+		f.mu.Lock()
+		if slices.Equal(f.sinks, current) {
+			return current, next, nil
+		}
+		latest := f.sinks
+		f.mu.Unlock()
+		// the sinks opened by the stale plan are not used
+		_ = next.without(current).close()
+		current = latest
+	}
+}
+
+// planSinks makes the sinks of the factory having the current ones, after the sinks of the config are applied
+// the current sinks declared the same are kept, and the others are opened;
+// when previous is given, the sinks it did not declare, e.g. added by AddSink, are kept too
+func (c *Config) planSinks(current sinks, previous *Config) (sinks, error) {
+	declared := make(map[string]bool, len(c.Sinks))
+	var next sinks
+	if previous != nil {
+		removed := make(map[string]bool, len(previous.Sinks))
+		for _, sc := range previous.Sinks {
+			removed[sc.Name] = true
+		}
+		for _, sc := range c.Sinks {
+			declared[sc.Name] = true
+		}
+		for _, s := range current {
+			if !removed[s.Name] && !declared[s.Name] {
+				next = append(next, s)
+			}
+		}
+		clear(declared)
+	}
+
+	for _, sc := range c.Sinks {
+		kept, err := func() (*sink, error) {
+			if declared[sc.Name] {
+				return nil, ErrSinkExists
+			}
+			declared[sc.Name] = true
+			for _, s := range current {
+				if s.Name == sc.Name && s.config != nil && s.config.equal(sc) {
+					return s, nil
+				}
+			}
+			return sc.open()
+		}()
+		if err != nil {
+			_ = next.without(current).close()
+			return nil, irr.Wrap(err, "invalid sink %q", sc.Name)
+		}
+		next = append(next, kept)
+	}
+	return next, nil
+}
+
+// open opens the sink declared
+func (sc SinkConfig) open() (*sink, error) {
+	formatter, err := newFormatter(sc.Formatter)
+	if err != nil {
+		return nil, err
+	}
+	level, err := parseLevel(sc.Level, logrus.InfoLevel)
+	if err != nil {
+		return nil, err
+	}
+	out, err := openOutput(sc.Output, sc.Rotate)
+	if err != nil {
+		return nil, err
+	}
+	return &sink{
+		Sink:   Sink{Name: sc.Name, Out: out, Formatter: formatter, Level: level, ChainPrefix: sc.ChainPrefix},
+		prefix: ParseChain(sc.ChainPrefix),
+		config: &sc,
+	}, nil
+}
+
+// equal tells whether the two declare the same sink
+func (sc SinkConfig) equal(other SinkConfig) bool {
+	rotate, otherRotate := sc.Rotate, other.Rotate
+	sc.Rotate, other.Rotate = nil, nil
+	if sc != other || (rotate == nil) != (otherRotate == nil) {
+		return false
+	}
+	return rotate == nil || *rotate == *otherRotate
+}
+
+// ---- private ----
+
+func newFormatter(name string) (logrus.Formatter, error) {
+	switch name {
+	case "", "text":
+		return &logrus.TextFormatter{FullTimestamp: true}, nil
+	case "json":
+		return &JSONFormatter{}, nil
+	case "tree":
+		return &TreeFormatter{}, nil
+	case "logrus-json":
+		return &logrus.JSONFormatter{}, nil
+	default:
+		return nil, irr.Wrap(ErrInvalidConfig, "unknown formatter %q", name)
+	}
+}
+
+func openOutput(output string, rotate *RotateConfig) (io.Writer, error) {
+	switch output {
+	case "", "stderr":
+		return os.Stderr, nil
+	case "stdout":
+		return os.Stdout, nil
+	case "discard":
+		return io.Discard, nil
+	}
+
+	var opts RotateOptions
+	if rotate != nil {
+		interval, err := parseDuration(rotate.Interval)
+		if err != nil {
+			return nil, err
+		}
+		opts = RotateOptions{
+			MaxSize:    rotate.MaxSizeMB << 20,
+			Interval:   interval,
+			MaxBackups: rotate.MaxBackups,
+			Compress:   rotate.Compress,
+		}
+	}
+	return NewRotatingFile(output, opts)
+}
+
+func parseLevel(name string, def logrus.Level) (logrus.Level, error) {
+	if name == "" {
+		return def, nil
+	}
+	level, err := logrus.ParseLevel(name)
+	if err != nil {
+		return def, irr.Wrap(ErrInvalidConfig, "unknown level %q", name)
+	}
+	return level, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, irr.Wrap(ErrInvalidConfig, "invalid duration %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,196 @@
+package wlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLoadConfig(t *testing.T) {
+	factory, err := LoadConfig(strings.NewReader(`{
+		"level": "warn",
+		"chain_levels": {"/payment/*": "debug"},
+		"formatter": "json",
+		"output": "discard",
+		"sinks": [{"name": "errors", "output": "discard", "level": "error"}],
+		"sampling": {"first": 10, "thereafter": 100, "tick": "2s"},
+		"tracing": true
+	}`))
+	if err != nil {
+		t.Fatalf("load config failed: %v", err)
+	}
+
+	if factory.Logger().GetLevel() != logrus.WarnLevel {
+		t.Errorf("level should be warn, got %s", factory.Logger().GetLevel())
+	}
+	if _, ok := factory.Logger().Formatter.(*JSONFormatter); !ok {
+		t.Errorf("formatter should be JSONFormatter, got %T", factory.Logger().Formatter)
+	}
+	if levels := factory.ChainLevels(); levels["/payment/*"] != logrus.DebugLevel {
+		t.Errorf("chain levels should be applied, got %v", levels)
+	}
+	if sinks := factory.Sinks(); len(sinks) != 1 || sinks[0].Name != "errors" || sinks[0].Level != logrus.ErrorLevel {
+		t.Errorf("sinks should be applied, got %+v", sinks)
+	}
+	if !factory.Tracing() {
+		t.Error("tracing should be enabled")
+	}
+	if getDefaultFactory() == factory {
+		t.Error("factory should not be installed as default unless declared")
+	}
+
+	if _, err = LoadConfig(strings.NewReader(`{"level": "loud"}`)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("unknown level should be rejected, got %v", err)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvLevel, "debug")
+	t.Setenv(EnvChainLevels, "/payment/*=trace, /poll=warn")
+	t.Setenv(EnvSampling, "100/1000/1s")
+	t.Setenv(EnvTracing, "true")
+
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("read config from env failed: %v", err)
+	}
+	if cfg.Level != "debug" || cfg.ChainLevels["/poll"] != "warn" || cfg.ChainLevels["/payment/*"] != "trace" {
+		t.Errorf("unexpected levels %+v", cfg)
+	}
+	if s := cfg.Sampling; s == nil || s.First != 100 || s.Thereafter != 1000 || s.Tick != "1s" {
+		t.Errorf("unexpected sampling %+v", s)
+	}
+	if !cfg.Tracing {
+		t.Error("tracing should be enabled")
+	}
+}
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wlog.json")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write config failed: %v", err)
+		}
+	}
+	write(`{"level": "info", "chain_levels": {"/payment/*": "debug"}}`)
+
+	factory, err := NewFactory(createDiscardLogger())
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	reloader := NewReloader(factory, path, 10*time.Millisecond)
+	if err := reloader.Start(); err != nil {
+		t.Fatalf("start reloader failed: %v", err)
+	}
+	defer reloader.Stop()
+	if levels := factory.ChainLevels(); levels["/payment/*"] != logrus.DebugLevel {
+		t.Errorf("chain levels should be applied on start, got %v", levels)
+	}
+
+	factory.SetChainLevel("/runtime", logrus.ErrorLevel)
+
+	write(`{"level": "warn", "chain_levels": {"/checkout": "trace"}}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for factory.Logger().GetLevel() != logrus.WarnLevel && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if factory.Logger().GetLevel() != logrus.WarnLevel {
+		t.Errorf("level should be reloaded to warn, got %s", factory.Logger().GetLevel())
+	}
+	if levels := factory.ChainLevels(); len(levels) != 2 || levels["/checkout"] != logrus.TraceLevel || levels["/runtime"] != logrus.ErrorLevel {
+		t.Errorf("chain levels of the config should be replaced, and the runtime ones kept, got %v", levels)
+	}
+
+	// sinks and the sampler are kept open while their config does not change
+	write(`{"level": "warn", "sinks": [{"name": "a", "output": "discard"}], "sampling": {"first": 1, "thereafter": 0}}`)
+	if err = reloader.Reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	factory.mu.RLock()
+	sink, s := factory.sinks[0], factory.sampler
+	factory.mu.RUnlock()
+
+	write(`{"level": "error", "sinks": [{"name": "a", "output": "discard"}], "sampling": {"first": 1, "thereafter": 0}}`)
+	if err = reloader.Reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	factory.mu.RLock()
+	keptSink, keptSampler := factory.sinks[0], factory.sampler
+	factory.mu.RUnlock()
+	if keptSink != sink || keptSampler != s {
+		t.Error("unchanged sinks and sampling should be kept")
+	}
+
+	write(`{"level": "error", "sinks": [{"name": "a", "output": "discard", "rotate": {"max_backups": 1}}], "sampling": {"first": 1, "thereafter": 0}}`)
+	if err = reloader.Reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	factory.mu.RLock()
+	reopened := factory.sinks[0]
+	factory.mu.RUnlock()
+	if reopened == sink {
+		t.Error("a sink whose rotation changed should be reopened")
+	}
+
+	diff := diffConfig(&Config{Level: "info", ChainLevels: map[string]string{"/a": "debug"}, Sinks: []SinkConfig{{Name: "s"}}},
+		&Config{Level: "warn", ChainLevels: map[string]string{"/b": "debug"}, Sinks: []SinkConfig{{Name: "s", Rotate: &RotateConfig{MaxBackups: 1}}}})
+	expected := Fields{"level": "info -> warn", "chain_levels./a": "debug -> -", "chain_levels./b": "- -> debug", "sinks.s": diff["sinks.s"]}
+	if len(diff) != len(expected) {
+		t.Errorf("diff should be %v, got %v", expected, diff)
+	}
+	if described, _ := diff["sinks.s"].(string); !strings.Contains(described, "rotate") {
+		t.Errorf("diff should report the rotation of sinks, got %v", diff)
+	}
+	for k, v := range expected {
+		if diff[k] != v {
+			t.Errorf("diff of %s should be %q, got %q", k, v, diff[k])
+		}
+	}
+}
+
+func TestConfigConcurrentSinks(t *testing.T) {
+	factory, err := NewFactory(createDiscardLogger())
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "a.log")
+	configs := [2]*Config{
+		{Sinks: []SinkConfig{{Name: "a", Output: path}}},
+		{Sinks: []SinkConfig{{Name: "a", Output: path, Rotate: &RotateConfig{MaxBackups: 1}}}},
+	}
+	if err = configs[0].Apply(factory); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	defer factory.Close(context.Background())
+
+	// sinks added while the config is applied, which reopens sink a each time, are not lost
+	const added = 1000
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < added; i++ {
+			_ = factory.AddSink(Sink{Name: "runtime-" + strconv.Itoa(i), Out: io.Discard})
+		}
+	}()
+	for i, running := 1, true; running; i++ {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		if err = configs[i%2].apply(factory, configs[(i-1)%2]); err != nil {
+			t.Fatalf("apply failed: %v", err)
+		}
+	}
+	if stats := factory.Sinks(); len(stats) != added+1 {
+		t.Errorf("expect %d sinks, got %d", added+1, len(stats))
+	}
+}
@@ -15,9 +15,28 @@ const (
 	EntryKeyWLogSrcValueLogger  = "logger"
 )
 
+// wlogKeyPrefix is the prefix of the entry keys used by wlog itself
+const wlogKeyPrefix = "wlog."
+
 // KeyFingerPrint is the key used to specify the fingerprint in the context
 const KeyFingerPrint = "wlog.fp"
 
+// KeySampleDropped is the key of the number of entries dropped by sampling
+const KeySampleDropped = "wlog.dropped"
+
+// Keys of the completion entry of a span
+const (
+	KeySpanDuration = "duration_ms"
+	KeySpanParent   = "parent"
+	KeySpanOutcome  = "outcome"
+)
+
+// Keys of the W3C trace context, see Factory.SetTracing
+const (
+	KeyTraceID = "trace_id"
+	KeySpanID  = "span_id"
+)
+
 // keyLocalMethod is the key used for local logging methods
 const keyLocalMethod = "wlog.local"
 
@@ -52,4 +71,31 @@ var (
 
 	// ErrArgumentTypeNotMatch is returned when the argument type doesn't match the expected type
 	ErrArgumentTypeNotMatch = irr.Error("invalid arguments: type error")
+
+	// ErrFactoryExists is returned when registering a factory under a name already taken
+	ErrFactoryExists = irr.Error("factory name already registered")
+
+	// ErrNilFactory is returned when registering a nil factory
+	ErrNilFactory = irr.Error("factory must be given")
+
+	// ErrLevelRequired is returned when changing the level of a factory without giving one
+	ErrLevelRequired = irr.Error("level must be given")
+
+	// ErrNoLogger is returned when an operation needs the logger of a factory built from an EntryMaker
+	ErrNoLogger = irr.Error("factory has no logger, its entries are made by an EntryMaker")
+
+	// ErrInvalidRedactRule is returned when a RedactRule has neither a key nor a value matcher
+	ErrInvalidRedactRule = irr.Error("redact rule matches nothing")
+
+	// ErrUnknownKey is returned when a KeyProvider has no key of an id
+	ErrUnknownKey = irr.Error("unknown encryption key")
+
+	// ErrInvalidCiphertext is returned when decrypting a value which was not encrypted by Encryption, or was altered
+	ErrInvalidCiphertext = irr.Error("invalid encrypted value")
+
+	// ErrSinkExists is returned when adding a sink whose name is taken
+	ErrSinkExists = irr.Error("sink already exists")
+
+	// ErrInvalidConfig is returned when a config can not be applied
+	ErrInvalidConfig = irr.Error("invalid config")
 )
@@ -0,0 +1,254 @@
+package wlog
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/khicago/irr"
+	"github.com/sirupsen/logrus"
+)
+
+// encryptedPrefix begins the encrypted values, which are written as "enc:v1:<key id>:<base64url of nonce and sealed>"
+const encryptedPrefix = "enc:v1:"
+
+// encryptFailed replaces the values which cannot be encrypted, so that they never are written in clear
+const encryptFailed = "[encryption failed]"
+
+var (
+	// keyIDPattern restricts the key ids, so that encrypted values need no quoting in any formatter
+	keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	// encryptedPattern finds the encrypted values in the output, with the key before them, which is bound to the value
+	// keys are JSON strings followed by ":", or words followed by "=", maybe colored, as the text formatters write them
+	encryptedPattern = regexp.MustCompile(`(?:"((?:[^"\\]|\\.)*)"\s*:\s*|` + ansiPattern + `([^\s="\x1b]+)` + ansiPattern + `=` + ansiPattern + `)` +
+		`("?` + encryptedPrefix + `[A-Za-z0-9._-]+:[A-Za-z0-9_-]+"?)`)
+)
+
+// ansiPattern matches the color escapes of the text formatters
+const ansiPattern = `(?:\x1b\[[0-9;]*m)*`
+
+type (
+	// KeyProvider provides the AES keys of Encryption, which are 16, 24 or 32 bytes long
+	// the id of a key is written with the values it encrypts, and must match [A-Za-z0-9._-]+
+	KeyProvider interface {
+		// EncryptionKey returns the key to encrypt with, and its id
+		EncryptionKey() (id string, key []byte, err error)
+		// DecryptionKey returns the key of the id
+		DecryptionKey(id string) ([]byte, error)
+	}
+
+	// StaticKeys is a KeyProvider of fixed keys, it encrypts with the key of Current
+	StaticKeys struct {
+		Current string
+		Keys    map[string][]byte
+	}
+
+	// Encryption encrypts column values with AES-GCM, see Factory.SetEncryption
+	// the encrypted values can be read back by Decrypt
+	Encryption struct {
+		Keys KeyProvider
+		// Columns are the keys of the columns to encrypt, exact or glob, e.g. "patient_*"
+		// the values marked by Sensitive are encrypted whatever their key
+		Columns []string
+	}
+
+	// SensitiveValue is a column value marked to be encrypted, see Sensitive
+	// it prints as a mask, so that it is not written in clear by a factory without Encryption
+	SensitiveValue struct {
+		value any
+	}
+)
+
+// Sensitive marks the value of a column to be encrypted, e.g. Field("patient_id", wlog.Sensitive(id))
+func Sensitive(value any) SensitiveValue {
+	return SensitiveValue{value: value}
+}
+
+func (s SensitiveValue) String() string {
+	return defaultRedactMask
+}
+
+// MarshalJSON writes the mask
+func (s SensitiveValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(defaultRedactMask)
+}
+
+// EncryptionKey returns the key of Current
+func (k StaticKeys) EncryptionKey() (string, []byte, error) {
+	key, err := k.DecryptionKey(k.Current)
+	return k.Current, key, err
+}
+
+// DecryptionKey returns the key of the id
+func (k StaticKeys) DecryptionKey(id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, irr.Wrap(ErrUnknownKey, "key id %s", id)
+	}
+	return key, nil
+}
+
+// SetEncryption sets the encryption of the columns of the Factory instance, nil disables it
+// every entry is encrypted when emitted, after it is redacted and before the hooks fire,
+// whatever added its fields, e.g. WithField; the columns in ctx stay as they are
+func (f *Factory) SetEncryption(e *Encryption) {
+	var copied *Encryption
+	if e != nil {
+		copied = &Encryption{Keys: e.Keys, Columns: append([]string(nil), e.Columns...)}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.encryptor = copied
+}
+
+// entry encrypts the selected fields of the entry being emitted, the fields of wlog are kept
+// entry.Data is the copy logrus makes for the emission, which is changed in place
+func (e *Encryption) entry(entry *logrus.Entry) {
+	for key, v := range entry.Data {
+		if isWLogKey(key) {
+			continue
+		}
+		if typed, ok := v.(*TypedValue); ok {
+			v = typed.Any()
+		}
+		sensitive, marked := v.(SensitiveValue)
+		if !marked && !e.selects(key) {
+			continue
+		}
+		if marked {
+			v = sensitive.value
+		}
+		sealed, err := e.encrypt(key, v)
+		if err != nil {
+			sealed = encryptFailed
+		}
+		entry.Data[key] = sealed
+	}
+}
+
+// selects reports whether the column of the key is encrypted
+func (e *Encryption) selects(key string) bool {
+	for _, pattern := range e.Columns {
+		if matchPattern(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// encrypt seals the JSON encoding of the value with the current key, the key of the column is bound as additional data,
+// so that a value moved to another column fails to decrypt
+func (e *Encryption) encrypt(column string, value any) (string, error) {
+	id, key, err := e.Keys.EncryptionKey()
+	if err != nil {
+		return "", err
+	}
+	if !keyIDPattern.MatchString(id) {
+		return "", irr.Wrap(ErrUnknownKey, "invalid key id %q", id)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	plain, err := appendJSONValue(nil, value)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, []byte(column))
+	return encryptedPrefix + id + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue decrypts a value written by Encryption in the column of the key,
+// and returns the JSON encoding of the original value
+func DecryptValue(keys KeyProvider, column, value string) (json.RawMessage, error) {
+	id, data, ok := strings.Cut(strings.TrimPrefix(strings.Trim(value, `"`), encryptedPrefix), ":")
+	if !ok || !strings.HasPrefix(strings.Trim(value, `"`), encryptedPrefix) {
+		return nil, ErrInvalidCiphertext
+	}
+	key, err := keys.DecryptionKey(id)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(column))
+	if err != nil {
+		return nil, irr.Wrap(ErrInvalidCiphertext, "%v", err)
+	}
+	return plain, nil
+}
+
+// Decrypt copies the log output from r to w, with the encrypted values replaced by the JSON encoding of the originals
+// it works on the output of the formatters of wlog and logrus, values failing to decrypt are kept,
+// and the first failure is returned
+func Decrypt(r io.Reader, w io.Writer, keys KeyProvider) error {
+	var failure error
+	reader := bufio.NewReader(r)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		line = encryptedPattern.ReplaceAllFunc(line, func(match []byte) []byte {
+			plain, err := decryptMatch(keys, match)
+			if err != nil {
+				if failure == nil {
+					failure = err
+				}
+				return match
+			}
+			return plain
+		})
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+		if readErr == io.EOF {
+			return failure
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// decryptMatch decrypts a match of encryptedPattern, and returns it with the value replaced
+func decryptMatch(keys KeyProvider, match []byte) ([]byte, error) {
+	sub := encryptedPattern.FindSubmatchIndex(match)
+	var column string
+	if sub[2] >= 0 {
+		// the JSON string of the key, quotes included
+		if err := json.Unmarshal(match[sub[2]-1:sub[3]+1], &column); err != nil {
+			return nil, ErrInvalidCiphertext
+		}
+	} else {
+		column = string(match[sub[4]:sub[5]])
+	}
+	plain, err := DecryptValue(keys, column, string(match[sub[6]:sub[7]]))
+	if err != nil {
+		return nil, err
+	}
+	return append(match[:sub[6]:sub[6]], plain...), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
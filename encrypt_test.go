@@ -0,0 +1,147 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestEncryption(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	logger.SetFormatter(&JSONFormatter{})
+
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	keys := StaticKeys{Current: "k2", Keys: map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 16),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	}}
+	factory.SetEncryption(&Encryption{Keys: keys, Columns: []string{"patient_*"}})
+
+	hook := &captureHook{}
+	logger.AddHook(hook)
+
+	_, ctx := factory.NewBuilder(context.Background()).Name("audit").
+		Field("patient_id", "P-1024").
+		Field("ward", Sensitive(7)).
+		Field("doctor", "house").
+		Branch()
+	factory.NewBuilder(ctx).Leaf().WithField("patient_name", "Alice").Info("record read")
+
+	out := buf.String()
+	for _, leaked := range []string{"P-1024", `"ward":7`, "Alice"} {
+		if strings.Contains(out, leaked) || strings.Contains(hook.String(), leaked) {
+			t.Fatalf("sensitive values should be encrypted before hooks, got %q and %q", out, hook.String())
+		}
+	}
+	if strings.Count(out, encryptedPrefix+"k2:") != 3 || !strings.Contains(out, `"doctor":"house"`) {
+		t.Errorf("selected columns only should be encrypted with the current key, got %q", out)
+	}
+	if columns := ColumnsFromCtx(ctx); len(columns) != 3 {
+		t.Errorf("columns in ctx should not be encrypted, got %v", columns)
+	}
+
+	decrypted := &bytes.Buffer{}
+	if err = Decrypt(strings.NewReader(out), decrypted, keys); err != nil {
+		t.Fatalf("decrypt failed: %v", err)
+	}
+	var line struct {
+		Fields map[string]any `json:"fields"`
+	}
+	if err = json.Unmarshal(decrypted.Bytes(), &line); err != nil {
+		t.Fatalf("decrypted output should be JSON, got %q: %v", decrypted, err)
+	}
+	if line.Fields["patient_id"] != "P-1024" || line.Fields["ward"] != float64(7) || line.Fields["patient_name"] != "Alice" {
+		t.Errorf("values should be decrypted, got %v", line.Fields)
+	}
+
+	// the key of the column is bound to the value
+	if err = Decrypt(strings.NewReader(strings.Replace(out, `"patient_id"`, `"doctor_id"`, 1)), &bytes.Buffer{}, keys); err == nil {
+		t.Error("a value moved to another column should fail to decrypt")
+	}
+
+	if err = Decrypt(strings.NewReader(out), &bytes.Buffer{}, StaticKeys{Keys: map[string][]byte{}}); err == nil {
+		t.Error("decrypt without the key should fail")
+	}
+	if fmt.Sprint(Sensitive("P-1024")) != defaultRedactMask {
+		t.Error("sensitive values should be masked without encryption")
+	}
+}
+
+func TestEncryptionText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	logger.SetFormatter(&logrus.TextFormatter{ForceColors: true})
+
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	keys := StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 16)}}
+	factory.SetEncryption(&Encryption{Keys: keys, Columns: []string{"patient_id"}})
+
+	log, ctx := factory.NewBuilder(context.Background()).Name("audit").Field("patient_id", "P-1024").Field("tenant", "t1").Branch()
+	log.Info("record read")
+
+	decrypted := &bytes.Buffer{}
+	if err = Decrypt(strings.NewReader(buf.String()), decrypted, keys); err != nil {
+		t.Fatalf("decrypt failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "P-1024") || !strings.Contains(decrypted.String(), `"P-1024"`) {
+		t.Errorf("values of colored text output should be decrypted, got %q", decrypted)
+	}
+
+	// columns encrypted in the entries are not sent in clear to other processes
+	carrier := mapCarrier{}
+	factory.Inject(ctx, carrier, "patient_id", "tenant")
+	if carrier[HeaderColumns] != "tenant=t1" {
+		t.Errorf("encrypted columns should not be propagated, got %q", carrier[HeaderColumns])
+	}
+}
+
+func TestEncryptionRedaction(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	logger.SetFormatter(&JSONFormatter{})
+
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	keys := StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 16)}}
+	factory.SetEncryption(&Encryption{Keys: keys, Columns: []string{"patient_email"}})
+	if err = factory.SetRedaction(&Redaction{Rules: PIIRules(RedactMask)}); err != nil {
+		t.Fatalf("set redaction failed: %v", err)
+	}
+
+	factory.NewBuilder(context.Background()).Name("audit").
+		Field("patient_email", "alice@example.com").
+		Field("contact", Sensitive("bob@example.com")).
+		Field("doctor_email", "house@example.com").
+		Leaf().Info("record read")
+
+	out := buf.String()
+	if strings.Contains(out, "@example.com") || !strings.Contains(out, `"doctor_email":"***"`) {
+		t.Fatalf("encrypted columns should be encrypted and the others redacted, got %q", out)
+	}
+	decrypted := &bytes.Buffer{}
+	if err = Decrypt(strings.NewReader(out), decrypted, keys); err != nil {
+		t.Fatalf("decrypt failed: %v", err)
+	}
+	for _, want := range []string{`"patient_email":"alice@example.com"`, `"contact":"bob@example.com"`} {
+		if !strings.Contains(decrypted.String(), want) {
+			t.Errorf("encrypted columns should not be redacted before encryption, want %s in %q", want, decrypted)
+		}
+	}
+}
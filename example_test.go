@@ -1,9 +1,13 @@
 package wlog
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestExample(t *testing.T) {
@@ -140,3 +144,132 @@ func BenchmarkDisableExample2(b *testing.B) {
 		d.Info("使用默认 wlog 实例打印")
 	}
 }
+
+func TestSpan(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("创建新的 factory 失败: %v", err)
+	}
+
+	_, ctx := factory.NewBuilder(context.Background()).Name("request").Branch()
+	_, spanCtx, end := factory.NewBuilder(ctx).Name("db_query").Span()
+	if _, ok := SpanStartFromCtx(spanCtx); !ok {
+		t.Fatal("span start should be cached in context")
+	}
+	end(errors.New("timeout"))
+	end(nil) // 重复结束不会再次打印
+
+	out := buf.String()
+	for _, want := range []string{KeySpanDuration + "=", KeySpanParent + "=/request", KeySpanOutcome + "=" + SpanOutcomeError, "wlog.fp=/request/db_query"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("span completion should contain %q, got %q", want, out)
+		}
+	}
+	if n := strings.Count(out, "span ended"); n != 1 {
+		t.Errorf("span should end once, got %d", n)
+	}
+}
+
+func TestWithDefault(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("创建新的 factory 失败: %v", err)
+	}
+
+	previous := Default()
+	WithDefault(factory, func() {
+		Leaf(context.Background(), "scoped").Info("使用临时默认实例打印")
+	})
+	if Default() != previous {
+		t.Error("默认实例应当被恢复")
+	}
+	if !strings.Contains(buf.String(), "wlog.fp=/scoped") {
+		t.Errorf("全局方法应当使用临时默认实例, got %q", buf.String())
+	}
+}
+
+// benchColumns 防止编译器优化掉被测试的列
+var benchColumns Columns
+
+func BenchmarkColumnsAny(b *testing.B) {
+	name := "alice" + strconv.Itoa(1)
+	cols := make(Columns, 0, 3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cols = cols[:0].Set(Column{Key: "user_id", Value: 1000 + i}, Column{Key: "name", Value: name}, Column{Key: "latency", Value: time.Duration(i)})
+	}
+	benchColumns = cols
+}
+
+func BenchmarkColumnsTyped(b *testing.B) {
+	name := "alice" + strconv.Itoa(1)
+	cols := make(Columns, 0, 3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cols = cols[:0].Set(Int("user_id", 1000+i), Str("name", name), Dur("latency", time.Duration(i)))
+	}
+	benchColumns = cols
+}
+
+func BenchmarkFieldAny(b *testing.B) {
+	logger := createDiscardLogger()
+	logger.SetFormatter(&JSONFormatter{})
+	factory, _ := NewFactory(logger)
+	ctx := context.Background()
+	name := "alice" + strconv.Itoa(1)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		factory.NewBuilder(ctx).Name("ok").Field("user_id", 1000+i).Field("name", name).Field("latency", time.Duration(i)).Leaf().Info("打印多个字段")
+	}
+}
+
+func BenchmarkFieldTyped(b *testing.B) {
+	logger := createDiscardLogger()
+	logger.SetFormatter(&JSONFormatter{})
+	factory, _ := NewFactory(logger)
+	ctx := context.Background()
+	name := "alice" + strconv.Itoa(1)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		factory.NewBuilder(ctx).Name("ok").Columns(Int("user_id", 1000+i), Str("name", name), Dur("latency", time.Duration(i))).Leaf().Info("打印多个字段")
+	}
+}
+
+func TestBuilderColumns(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("创建新的 factory 失败: %v", err)
+	}
+
+	// Field 与 Fields 的结果需要被保留
+	_, ctx := factory.NewBuilder(context.Background()).Name("req").
+		Field("a", 1).Fields(Fields{"b": 2}).Field("c", 3).Branch()
+	if cols := ColumnsFromCtx(ctx); len(cols) != 3 {
+		t.Fatalf("所有字段都应当被保留, got %v", cols)
+	}
+
+	// 合并列时不能修改 ctx 中缓存的列
+	cached := ColumnsFromCtx(ctx)
+	factory.NewBuilder(ctx).Name("leaf").Field("a", 10).Field("d", 4).Leaf().Info("覆盖字段")
+	if cached[0].Value != 1 || len(ColumnsFromCtx(ctx)) != 3 {
+		t.Errorf("ctx 中的列不应被修改, got %v", ColumnsFromCtx(ctx))
+	}
+	if out := buf.String(); !strings.Contains(out, "a=10") || !strings.Contains(out, "d=4") {
+		t.Errorf("叶子节点应当使用新的字段, got %q", out)
+	}
+
+	left, right := Columns{{Key: "x", Value: 1}}, Columns{{Key: "x", Value: 2}, {Key: "y", Value: 3}}
+	combined := left.Combine(right)
+	if left[0].Value != 1 || len(left) != 1 || len(combined) != 2 || combined[0].Value != 2 {
+		t.Errorf("Combine 不应修改输入, got left=%v combined=%v", left, combined)
+	}
+}
@@ -15,6 +15,32 @@ type Factory struct {
 	entryMaker   EntryMaker
 	defaultEntry *logrus.Entry
 	mu           sync.RWMutex
+
+	// chainLevels overrides the level of some chains, see SetChainLevel
+	chainLevels chainLevels
+	followers   map[followerKey]*logrus.Logger
+	followMu    sync.Mutex
+
+	// sampler limits the entries per chain and level, see SetSampling
+	sampler *sampler
+
+	// tracing enables trace_id and span_id, see SetTracing
+	tracing bool
+
+	// redactor removes sensitive data from columns and messages, see SetRedaction
+	redactor *redactor
+
+	// encryption encrypts the values of some columns, see SetEncryption
+	encryptor *Encryption
+
+	// async is the asynchronous output of the factory, see SetAsync
+	async *AsyncWriter
+
+	// sinks replace the output of the logger when there are any, see AddSink
+	sinks sinks
+
+	// closed is set by Close, so that closing again does nothing
+	closed bool
 }
 
 // SetEntryMaker updates the EntryMaker of the Factory instance
@@ -34,10 +60,31 @@ func (f *Factory) Logger() *logrus.Logger {
 }
 
 // SetLevel sets the logging level for the Factory instance
+// chains overridden by SetChainLevel keep their own level
 func (f *Factory) SetLevel(level logrus.Level) {
 	f.mu.RLock()
-	defer f.mu.RUnlock()
-	f.Logger().SetLevel(level)
+	base := f.defaultEntry
+	f.mu.RUnlock()
+	if base != nil {
+		base.Logger.SetLevel(level)
+	}
+}
+
+// enabled reports whether entries of the chain at the level would be logged
+// entries in a tail buffering scope are always taken, since the scope decides later
+func (f *Factory) enabled(ctx context.Context, chain Chain, level logrus.Level) bool {
+	if tailFromCtx(ctx) != nil {
+		return true
+	}
+
+	f.mu.RLock()
+	base := f.defaultEntry
+	f.mu.RUnlock()
+	if base == nil || base.Logger == nil {
+		// the logger of entries made by EntryMaker is unknown until they are made
+		return true
+	}
+	return f.levelOf(base.Logger, chain) >= level
 }
 
 // LoggerSource defines the types that can be used to create a Factory
@@ -0,0 +1,92 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSinks(t *testing.T) {
+	factory, err := NewFactory(createDiscardLogger())
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	file, stderr, payment := &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}
+	for _, s := range []Sink{
+		{Name: "file", Out: file, Formatter: &JSONFormatter{}, Level: logrus.InfoLevel},
+		{Name: "stderr", Out: stderr, Level: logrus.ErrorLevel},
+		{Name: "payment", Out: payment, Level: logrus.InfoLevel, ChainPrefix: "/payment"},
+	} {
+		if err = factory.AddSink(s); err != nil {
+			t.Fatalf("add sink failed: %v", err)
+		}
+	}
+	if err = factory.AddSink(Sink{Name: "file", Out: file}); err != ErrSinkExists {
+		t.Errorf("duplicated sink name should be rejected, got %v", err)
+	}
+
+	ctx := context.Background()
+	factory.NewBuilder(ctx).Name("order").Leaf().Info("order info")
+	factory.NewBuilder(ctx).Name("payment", "refund").Leaf().Error("refund error")
+
+	if n := strings.Count(file.String(), "\n"); n != 2 || !strings.HasPrefix(file.String(), "{") {
+		t.Errorf("file sink should get both entries as JSON, got %q", file.String())
+	}
+	if strings.Contains(stderr.String(), "order info") || !strings.Contains(stderr.String(), "refund error") {
+		t.Errorf("stderr sink should only get errors, got %q", stderr.String())
+	}
+	if strings.Contains(payment.String(), "order info") || !strings.Contains(payment.String(), "refund error") {
+		t.Errorf("payment sink should only get the payment chain, got %q", payment.String())
+	}
+
+	stats := factory.Sinks()
+	if len(stats) != 3 || stats[0].Written != 2 || stats[1].Written != 1 {
+		t.Errorf("unexpected sink stats %+v", stats)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	if f, ok := Get(RegistryNameDefault); !ok || f != Default() {
+		t.Error("the default factory should be registered")
+	}
+	if err := Register(RegistryNameLocal, Default()); err != ErrFactoryExists {
+		t.Errorf("reserved names should not be registered, got %v", err)
+	}
+	if err := Register("orders", nil); err != ErrNilFactory {
+		t.Errorf("nil factory should not be registered, got %v", err)
+	}
+	if _, ok := Get("orders"); ok {
+		t.Error("nil factory should not be found")
+	}
+
+	factory, err := NewFactory(createDiscardLogger())
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	WithDefault(factory, func() {
+		if f, _ := Get(RegistryNameDefault); f != factory {
+			t.Error("the default name should follow SetDefault")
+		}
+	})
+
+	if err = Register("orders", factory); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	var names []string
+	Range(func(name string, f *Factory) bool {
+		names = append(names, name)
+		return true
+	})
+	if strings.Join(names, ",") != "default,local,orders" {
+		t.Errorf("registered names should be sorted, got %v", names)
+	}
+	if f, ok := Unregister("orders"); !ok || f != factory {
+		t.Error("unregister should return the factory")
+	}
+	if _, ok := Get("orders"); ok {
+		t.Error("unregistered factory should not be found")
+	}
+}
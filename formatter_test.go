@@ -0,0 +1,140 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTreeFormatter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	logger.SetFormatter(&TreeFormatter{DisableColors: true, ChainWidth: 12})
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+
+	_, ctx := factory.NewBuilder(context.Background()).Name("a").Branch()
+	factory.NewBuilder(ctx).Name("b").Field("z", 1).Field("k", "two words").Leaf().Info("hello")
+
+	line := buf.String()
+	if !strings.Contains(line, " INFO  /a/b           hello k=\"two words\" method_=- z=1\n") {
+		t.Errorf("unexpected tree line %q", line)
+	}
+}
+
+func TestJSONFormatter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	logger.SetFormatter(&JSONFormatter{Keys: JSONKeys{Message: "message"}})
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+
+	_, ctx := factory.NewBuilder(context.Background()).Name("a").Branch()
+	factory.NewBuilder(ctx).Name("b").Field("z", 1.5).Field("k", "quote\"\n").Leaf().
+		WithError(errors.New("boom")).Info("hello")
+
+	line := buf.String()
+	var decoded map[string]any
+	if err = json.Unmarshal([]byte(line), &decoded); err != nil {
+		t.Fatalf("output should be valid JSON, got %q: %v", line, err)
+	}
+	want := `"message":"hello","chain":"/a/b","chain_nodes":["a","b"],` +
+		`"fields":{"error":"boom","k":"quote\"\n","z":1.5},"wlog":{"method":"-","src":"default"}}`
+	if !strings.HasPrefix(line, `{"ts":"`) || !strings.Contains(line, want) {
+		t.Errorf("unexpected JSON line %q", line)
+	}
+}
+
+func TestTypedColumns(t *testing.T) {
+	at := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	cols := Columns{
+		Int("user_id", 12345), Int64("seq", -7), Uint64("size", 1<<40), Float64("ratio", 0.5),
+		Str("name", "alice"), Bool("admin", true), Dur("latency", 1500*time.Millisecond), Time("at", at),
+		Err(errors.New("boom")), Any("tags", []string{"a"}),
+	}
+	expected := Fields{
+		"user_id": 12345, "seq": int64(-7), "size": uint64(1 << 40), "ratio": 0.5,
+		"name": "alice", "admin": true, "latency": 1500 * time.Millisecond, "at": at,
+		"error": "boom", "tags": "[a]",
+	}
+	for _, col := range cols {
+		if got := fmt.Sprint(col.Any()); got != fmt.Sprint(expected[col.Key]) {
+			t.Errorf("value of %s should be %v, got %v", col.Key, expected[col.Key], got)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	logger.SetFormatter(&JSONFormatter{})
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	factory.NewBuilder(context.Background()).Name("typed").Columns(cols...).Leaf().Info("typed columns")
+
+	var line struct {
+		Fields map[string]any `json:"fields"`
+	}
+	if err = json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("output should be JSON, got %q: %v", buf, err)
+	}
+	for key, value := range map[string]any{
+		"user_id": float64(12345), "seq": float64(-7), "ratio": 0.5, "name": "alice", "admin": true,
+		"latency": "1.5s", "at": at.Format(time.RFC3339Nano), "error": "boom",
+	} {
+		if line.Fields[key] != value {
+			t.Errorf("field %s should be %v, got %v", key, value, line.Fields[key])
+		}
+	}
+
+	buf.Reset()
+	logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+	_, ctx := factory.NewBuilder(context.Background()).Name("typed").Columns(Int("user_id", 12345)).Branch()
+	if col := ColumnsFromCtx(ctx)[0]; col.kind != kindAny || col.Value != 12345 {
+		t.Errorf("typed columns cached in a branch should be boxed once, got %+v", col)
+	}
+	factory.NewBuilder(ctx).Name("leaf").Columns(Dur("latency", time.Second)).Leaf().Info("typed text")
+	if out := buf.String(); !strings.Contains(out, "user_id=12345") || !strings.Contains(out, "latency=1s") {
+		t.Errorf("text output should print typed values, got %q", out)
+	}
+
+	// hooks of other packages switch on the types of the values, they get them boxed
+	hook := &typesHook{}
+	logger.AddHook(hook)
+	factory.NewBuilder(context.Background()).Name("hooked").Columns(Int("user_id", 12345), Dur("latency", time.Second)).Leaf().Info("typed hook")
+	if hook.types["user_id"] != "int" || hook.types["latency"] != "time.Duration" {
+		t.Errorf("hooks should see boxed values, got %v", hook.types)
+	}
+	if out := buf.String(); !strings.Contains(out, "msg=\"typed hook\" latency=1s method_=- user_id=12345") {
+		t.Errorf("text output should print the boxed values, got %q", out)
+	}
+}
+
+// typesHook records the types of the values of the last entry
+type typesHook struct {
+	types map[string]string
+}
+
+func (h *typesHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *typesHook) Fire(entry *logrus.Entry) error {
+	h.types = make(map[string]string, len(entry.Data))
+	for key, value := range entry.Data {
+		h.types[key] = fmt.Sprintf("%T", value)
+	}
+	return nil
+}
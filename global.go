@@ -21,6 +21,29 @@ func getDefaultFactory() *Factory {
 	return defaultFactory.Load().(*Factory)
 }
 
+// Default returns the default wlog instance, which By, Leaf, Branch, Detach and Common log with
+func Default() *Factory {
+	return getDefaultFactory()
+}
+
+// SetDefault replaces the default wlog instance, and returns the previous one
+// the swap is atomic, builders already created keep the factory they were created with
+// nil is ignored, and the current default instance is returned
+func SetDefault(f *Factory) *Factory {
+	if f == nil {
+		return getDefaultFactory()
+	}
+	return defaultFactory.Swap(f).(*Factory)
+}
+
+// WithDefault runs fn with f as the default wlog instance, and restores the previous one after, even if fn panics
+// the default instance is process wide, thus tests using it should not run in parallel
+func WithDefault(f *Factory, fn func()) {
+	previous := SetDefault(f)
+	defer SetDefault(previous)
+	fn()
+}
+
 // SetEntryMaker sets the EntryMaker of default wlog instance
 func SetEntryMaker(em EntryMaker) {
 	getDefaultFactory().SetEntryMaker(em)
@@ -42,6 +65,19 @@ func Branch(ctx context.Context, fingerPrints ...string) (WLog, context.Context)
 	return By(ctx, fingerPrints...).Branch()
 }
 
+// BufferedBranch - create a log entry like Branch, and open a tail buffering scope on the returned context
+// entries filtered out by level are held, and only written if an entry at Error or higher is logged in the scope
+// call the returned function to close the scope
+func BufferedBranch(ctx context.Context, fingerPrints ...string) (WLog, context.Context, func()) {
+	return By(ctx, fingerPrints...).BufferedBranch()
+}
+
+// Span - create a log entry like Branch, and record the start time of the span in the returned context
+// call the returned SpanEnd to log the completion of the span, with its duration and outcome
+func Span(ctx context.Context, fingerPrints ...string) (WLog, context.Context, SpanEnd) {
+	return By(ctx, fingerPrints...).Span()
+}
+
 // Detach - create a log entry from the given context and fingerprints (using the default wlog instance)
 // method and fingerprint will be transferred to ctx, thus the mfp works in future
 func Detach(ctx context.Context, fingerPrints ...string) (WLog, context.Context) {
@@ -0,0 +1,18 @@
+module github.com/khicago/wlog/grpcwlog
+
+go 1.22.3
+
+require (
+	github.com/khicago/wlog v0.0.0-20261017011012-0ce401dae7a8
+	github.com/sirupsen/logrus v1.9.3
+	google.golang.org/grpc v1.67.3
+)
+
+require (
+	github.com/khicago/irr v0.0.0-20240309052027-df085c2216f6 // indirect
+	golang.org/x/net v0.28.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/text v0.17.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240814211410-ddb44dafa142 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
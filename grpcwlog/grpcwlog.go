@@ -0,0 +1,235 @@
+// Package grpcwlog provides gRPC interceptors which open a wlog branch for every call,
+// and carry the chain and columns through gRPC metadata
+package grpcwlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/khicago/wlog"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Keys of the columns of the completion entry
+const (
+	KeyMethod  = "grpc.method"
+	KeyCode    = "grpc.code"
+	KeyLatency = "latency_ms"
+)
+
+type (
+	// Option configures the interceptors
+	Option func(*options)
+
+	options struct {
+		factory *wlog.Factory
+		keys    []string
+	}
+)
+
+// WithFactory logs with the given factory instead of the default one
+func WithFactory(f *wlog.Factory) Option {
+	return func(o *options) {
+		o.factory = f
+	}
+}
+
+// WithColumns whitelists the columns sent by the client interceptors, and accepted by the server interceptors
+func WithColumns(keys ...string) Option {
+	return func(o *options) {
+		o.keys = keys
+	}
+}
+
+// UnaryServerInterceptor restores the propagated chain and columns, branches the context
+// with the full method name, and logs the completion of the call
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		log, ctx := o.branch(o.extract(ctx), info.FullMethod)
+		resp, err := handler(ctx, req)
+		complete(log, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor works like UnaryServerInterceptor for streaming calls
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		log, ctx := o.branch(o.extract(ss.Context()), info.FullMethod)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		complete(log, start, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor branches the context with the full method name, sends the chain and
+// the whitelisted columns through the outgoing metadata, and logs the completion of the call
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		start := time.Now()
+		log, ctx := o.branch(ctx, method)
+		err := invoker(o.inject(ctx), method, req, reply, cc, callOpts...)
+		complete(log, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor works like UnaryClientInterceptor for streaming calls, the completion is logged
+// when receiving from the stream ends, after the only response of a call without server streaming,
+// or when ctx is done before, e.g. the stream is abandoned and ctx canceled
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		log, ctx := o.branch(ctx, method)
+		cs, err := streamer(o.inject(ctx), desc, cc, method, callOpts...)
+		if err != nil {
+			complete(log, start, err)
+			return nil, err
+		}
+		s := &clientStream{ClientStream: cs, log: log, start: start, unary: !desc.ServerStreams}
+		s.stop = context.AfterFunc(ctx, func() {
+			s.finish(status.FromContextError(ctx.Err()).Err())
+		})
+		return s, nil
+	}
+}
+
+// ---- private ----
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) branch(ctx context.Context, method string) (wlog.WLog, context.Context) {
+	if o.factory == nil {
+		return wlog.By(ctx, method).Field(KeyMethod, method).Branch()
+	}
+	return o.factory.NewBuilder(ctx).Name(method).Field(KeyMethod, method).Branch()
+}
+
+// inject writes the chain and the whitelisted columns of ctx into its outgoing metadata
+func (o *options) inject(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	if o.factory == nil {
+		wlog.Inject(ctx, carrier(md), o.keys...)
+	} else {
+		o.factory.Inject(ctx, carrier(md), o.keys...)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// extract restores the chain and the whitelisted columns of the incoming metadata into ctx
+func (o *options) extract(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return wlog.Extract(ctx, carrier(md), o.keys...)
+}
+
+// complete logs the completion of a call
+func complete(log wlog.WLog, start time.Time, err error) {
+	code := status.Code(err)
+	entry := log.WithFields(wlog.Fields{
+		KeyCode:    code.String(),
+		KeyLatency: float64(time.Since(start).Microseconds()) / 1000,
+	})
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Log(levelOf(code), "call completed")
+}
+
+// levelOf returns the level of the completion entry of the code
+func levelOf(code codes.Code) logrus.Level {
+	switch code {
+	case codes.OK:
+		return logrus.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.FailedPrecondition,
+		codes.OutOfRange, codes.ResourceExhausted, codes.Aborted:
+		return logrus.WarnLevel
+	default:
+		return logrus.ErrorLevel
+	}
+}
+
+// carrier adapts gRPC metadata to wlog.Carrier
+type carrier metadata.MD
+
+func (c carrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c carrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// serverStream replaces the context of the stream with the branched one
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// clientStream logs the completion of the call once receiving ends
+type clientStream struct {
+	grpc.ClientStream
+	log   wlog.WLog
+	start time.Time
+	unary bool
+	stop  func() bool
+	once  sync.Once
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case errors.Is(err, io.EOF):
+		s.stop()
+		s.finish(nil)
+	case err != nil:
+		s.stop()
+		s.finish(err)
+	case s.unary:
+		// the server sends a single response, there is nothing left to receive
+		s.stop()
+		s.finish(nil)
+	}
+	return err
+}
+
+// finish logs the completion once
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		complete(s.log, s.start, err)
+	})
+}
@@ -0,0 +1,216 @@
+package grpcwlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/khicago/wlog"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// syncBuffer is written by the server and read by the test
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newFactory(t *testing.T, out *syncBuffer) *wlog.Factory {
+	logger := logrus.New()
+	logger.SetOutput(out)
+	logger.SetFormatter(&logrus.TextFormatter{DisableColors: true})
+	factory, err := wlog.NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	return factory
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	serverOut, clientOut := &syncBuffer{}, &syncBuffer{}
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(WithFactory(newFactory(t, serverOut)), WithColumns("tenant"))),
+		grpc.StreamInterceptor(StreamServerInterceptor(WithFactory(newFactory(t, serverOut)))),
+	)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(WithFactory(newFactory(t, clientOut)), WithColumns("tenant"))),
+	)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	_, ctx := wlog.By(context.Background(), "gateway").Field("tenant", "t1").Branch()
+	if _, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+
+	method := healthpb.Health_Check_FullMethodName
+	for name, out := range map[string]string{"client": clientOut.String(), "server": serverOut.String()} {
+		for _, want := range []string{"call completed", KeyCode + "=OK", KeyMethod + "=" + method, "tenant=t1", "wlog.fp=/gateway/" + method} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s log should contain %q, got %q", name, want, out)
+			}
+		}
+	}
+}
+
+// streamServer echoes client streams, sends two responses on server streams,
+// and holds bidirectional streams until the client goes away
+type streamServer struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (streamServer) StreamingInputCall(stream grpc.ClientStreamingServer[testpb.StreamingInputCallRequest, testpb.StreamingInputCallResponse]) error {
+	var size int32
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&testpb.StreamingInputCallResponse{AggregatedPayloadSize: size})
+		}
+		if err != nil {
+			return err
+		}
+		size += int32(len(req.GetPayload().GetBody()))
+	}
+}
+
+func (streamServer) StreamingOutputCall(_ *testpb.StreamingOutputCallRequest, stream grpc.ServerStreamingServer[testpb.StreamingOutputCallResponse]) error {
+	for i := 0; i < 2; i++ {
+		if err := stream.Send(&testpb.StreamingOutputCallResponse{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (streamServer) FullDuplexCall(stream grpc.BidiStreamingServer[testpb.StreamingOutputCallRequest, testpb.StreamingOutputCallResponse]) error {
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return err
+		}
+	}
+}
+
+// completion returns the completion line of the method in out
+func completion(out, method string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "call completed") && strings.Contains(line, KeyMethod+"="+method) {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	serverOut, clientOut := &syncBuffer{}, &syncBuffer{}
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.StreamInterceptor(StreamServerInterceptor(WithFactory(newFactory(t, serverOut)))))
+	testpb.RegisterTestServiceServer(server, streamServer{})
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(WithFactory(newFactory(t, clientOut)))),
+	)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	client := testpb.NewTestServiceClient(conn)
+
+	// client streaming, completed by CloseAndRecv
+	in, err := client.StreamingInputCall(context.Background())
+	if err != nil {
+		t.Fatalf("open stream failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = in.Send(&testpb.StreamingInputCallRequest{Payload: &testpb.Payload{Body: []byte("abc")}}); err != nil {
+			t.Fatalf("send failed: %v", err)
+		}
+	}
+	if resp, err := in.CloseAndRecv(); err != nil || resp.GetAggregatedPayloadSize() != 6 {
+		t.Fatalf("close and receive failed: %v, %v", resp, err)
+	}
+
+	// server streaming, completed by io.EOF
+	out, err := client.StreamingOutputCall(context.Background(), &testpb.StreamingOutputCallRequest{})
+	if err != nil {
+		t.Fatalf("open stream failed: %v", err)
+	}
+	for err == nil {
+		_, err = out.Recv()
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("receive failed: %v", err)
+	}
+
+	// bidirectional, abandoned by canceling its context
+	ctx, cancel := context.WithCancel(context.Background())
+	duplex, err := client.FullDuplexCall(ctx)
+	if err != nil {
+		t.Fatalf("open stream failed: %v", err)
+	}
+	if err = duplex.Send(&testpb.StreamingOutputCallRequest{}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	cancel()
+
+	for _, method := range []string{testpb.TestService_StreamingInputCall_FullMethodName, testpb.TestService_StreamingOutputCall_FullMethodName} {
+		if line := completion(clientOut.String(), method); !strings.Contains(line, KeyCode+"=OK") {
+			t.Errorf("the client should log the completion of %s, got %q", method, clientOut.String())
+		}
+	}
+
+	method := testpb.TestService_FullDuplexCall_FullMethodName
+	deadline := time.Now().Add(time.Second)
+	for completion(clientOut.String(), method) == "" || completion(serverOut.String(), method) == "" {
+		if time.Now().After(deadline) {
+			t.Fatalf("the abandoned stream should be logged on both sides, client %q, server %q", clientOut.String(), serverOut.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if line := completion(clientOut.String(), method); !strings.Contains(line, KeyCode+"=Canceled") {
+		t.Errorf("the abandoned stream should be logged as canceled, got %q", line)
+	}
+	for _, method := range []string{testpb.TestService_StreamingInputCall_FullMethodName, testpb.TestService_StreamingOutputCall_FullMethodName} {
+		if line := completion(serverOut.String(), method); !strings.Contains(line, KeyCode+"=OK") {
+			t.Errorf("the server should log the completion of %s, got %q", method, serverOut.String())
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Package httpmw provides net/http middleware which opens a wlog branch for every request
+package httpmw
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/khicago/wlog"
+	"github.com/sirupsen/logrus"
+)
+
+// Keys of the columns attached to the request branch
+const (
+	KeyMethod     = "http.method"
+	KeyPath       = "http.path"
+	KeyRemoteAddr = "http.remote_addr"
+	KeyRequestID  = "request_id"
+	KeyStatus     = "http.status"
+	KeyBytes      = "http.bytes"
+	KeyLatency    = "latency_ms"
+	KeyPanic      = "panic"
+)
+
+// HeaderRequestID is the default header to read the request id from
+const HeaderRequestID = "X-Request-Id"
+
+type (
+	// FingerPrint names the branch of a request by chain nodes, which must not contain "/"
+	// the names should be bounded, since the sampler and the chain levels keep a state per chain
+	FingerPrint func(r *http.Request) []string
+
+	// Option configures the middleware
+	Option func(*options)
+
+	options struct {
+		factory         *wlog.Factory
+		fingerPrint     FingerPrint
+		requestIDHeader string
+	}
+)
+
+// Method names the branch by the method of the request, e.g. "/GET", it is the default
+func Method(r *http.Request) []string {
+	return []string{r.Method}
+}
+
+// Pattern names the branch by the method and the pattern of the mux matching the request,
+// e.g. "/GET/users/{id}" for the pattern "GET /users/{id}", requests matching no pattern are named by the method
+func Pattern(mux *http.ServeMux) FingerPrint {
+	return func(r *http.Request) []string {
+		_, pattern := mux.Handler(r)
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			method, path = r.Method, pattern
+		}
+		return append([]string{method}, wlog.ParseChain(path)...)
+	}
+}
+
+// MethodPath names the branch by the method and the path, e.g. "/GET/users/42"
+// every distinct path makes a distinct chain, thus it only suits services with few paths
+func MethodPath(r *http.Request) []string {
+	return append([]string{r.Method}, wlog.ParseChain(r.URL.Path)...)
+}
+
+// Route names every request of a handler by the same fixed route, e.g. Route("/users/{id}")
+func Route(route string) FingerPrint {
+	nodes := wlog.ParseChain(route)
+	return func(*http.Request) []string {
+		return nodes
+	}
+}
+
+// WithFactory logs with the given factory instead of the default one
+func WithFactory(f *wlog.Factory) Option {
+	return func(o *options) {
+		o.factory = f
+	}
+}
+
+// WithFingerPrint sets how a request branch is named
+func WithFingerPrint(fp FingerPrint) Option {
+	return func(o *options) {
+		o.fingerPrint = fp
+	}
+}
+
+// WithRequestIDHeader sets the header carrying the request id, HeaderRequestID by default
+func WithRequestIDHeader(header string) Option {
+	return func(o *options) {
+		o.requestIDHeader = header
+	}
+}
+
+// Middleware branches the context of every request, and writes an access log entry when the handler returns,
+// or panics, in which case the entry is at Error with the panic, and the panic goes on
+// a request without request id gets a generated one, which is also sent back in the response header
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		fingerPrint:     Method,
+		requestIDHeader: HeaderRequestID,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(o.requestIDHeader)
+			if requestID == "" {
+				requestID = newRequestID()
+				w.Header().Set(o.requestIDHeader, requestID)
+			}
+
+			log, ctx := o.builder(r).
+				Field(KeyMethod, r.Method).
+				Field(KeyPath, r.URL.Path).
+				Field(KeyRemoteAddr, r.RemoteAddr).
+				Field(KeyRequestID, requestID).
+				Branch()
+
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				level, fields := levelOf(rw.status), wlog.Fields{
+					KeyStatus:  rw.status,
+					KeyBytes:   rw.bytes,
+					KeyLatency: float64(time.Since(start).Microseconds()) / 1000,
+				}
+				p := recover()
+				if p != nil && p != http.ErrAbortHandler {
+					level, fields[KeyStatus], fields[KeyPanic] = logrus.ErrorLevel, http.StatusInternalServerError, p
+				}
+				log.WithFields(fields).Log(level, "request served")
+				if p != nil {
+					panic(p) // net/http recovers it, and closes the connection
+				}
+			}()
+			next.ServeHTTP(rw, r.WithContext(ctx))
+		})
+	}
+}
+
+func (o *options) builder(r *http.Request) *wlog.Builder {
+	if o.factory == nil {
+		return wlog.By(r.Context(), o.fingerPrint(r)...)
+	}
+	return o.factory.NewBuilder(r.Context()).Name(o.fingerPrint(r)...)
+}
+
+// levelOf returns the level of the access log entry of the status code
+func levelOf(status int) logrus.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return logrus.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return logrus.WarnLevel
+	default:
+		return logrus.InfoLevel
+	}
+}
+
+func newRequestID() string {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// responseWriter records the status code and the bytes written
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status, rw.wroteHeader = status, true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(p)
+	rw.bytes += n
+	return n, err
+}
+
+// Flush implements http.Flusher, it does nothing when the underlying writer cannot flush
+func (rw *responseWriter) Flush() {
+	rw.wroteHeader = true
+	_ = http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker, the access log of a hijacked request has the status 101
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil && !rw.wroteHeader {
+		rw.status, rw.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return conn, buf, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
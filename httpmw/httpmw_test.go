@@ -0,0 +1,143 @@
+package httpmw
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/khicago/wlog"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMiddleware(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetOutput(buf)
+	logger.SetFormatter(&logrus.TextFormatter{DisableColors: true})
+	factory, err := wlog.NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+
+	handler := Middleware(WithFactory(factory), WithFingerPrint(Route("users")))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if chain := wlog.ChainFromCtx(r.Context()); chain.String() != "/users" {
+				t.Errorf("request context should be branched, got %s", chain)
+			}
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("missing"))
+		}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	handler.ServeHTTP(rec, req)
+
+	out := buf.String()
+	for _, want := range []string{"level=warning", KeyStatus + "=404", KeyBytes + "=7", KeyRequestID + "=req-1", KeyPath + "=/users/42", "wlog.fp=/users"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("access log should contain %q, got %q", want, out)
+		}
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	var got wlog.Chain
+	var gotCols wlog.Columns
+	server := httptest.NewServer(Extract(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ctx := wlog.Branch(r.Context(), "inventory")
+		got, gotCols = wlog.ChainFromCtx(ctx), wlog.ColumnsFromCtx(ctx)
+	}), "tenant"))
+	defer server.Close()
+
+	_, ctx := wlog.By(context.Background(), "gateway").Field("tenant", "t1").Field("secret", "s").Branch()
+	_, ctx = wlog.Branch(ctx, "order")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	client := &http.Client{Transport: Transport(nil, "tenant", "secret")}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if got.String() != "/gateway/order/inventory" {
+		t.Errorf("chain should continue across the boundary, got %s", got)
+	}
+	if len(gotCols) != 1 || gotCols[0].Key != "tenant" || gotCols[0].Value != "t1" {
+		t.Errorf("only columns whitelisted on both sides should be propagated, got %v", gotCols)
+	}
+}
+
+func TestMiddlewarePanicAndFlush(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetOutput(buf)
+	logger.SetFormatter(&logrus.TextFormatter{DisableColors: true})
+	factory, err := wlog.NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+
+	handler := Middleware(WithFactory(factory))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("the writer should still be a http.Flusher")
+		}
+		_, _ = w.Write([]byte("event"))
+		flusher.Flush()
+		if _, ok = w.(http.Hijacker); !ok {
+			t.Error("the writer should still be a http.Hijacker")
+		}
+		panic("boom")
+	}))
+
+	rec := httptest.NewRecorder()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("the panic should go on")
+			}
+		}()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	}()
+
+	if !rec.Flushed {
+		t.Error("flush should reach the underlying writer")
+	}
+	out := buf.String()
+	for _, want := range []string{"level=error", KeyStatus + "=500", KeyPanic + "=boom", "wlog.fp=/GET"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("access log should contain %q, got %q", want, out)
+		}
+	}
+}
+
+func TestPatternFingerPrint(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetOutput(buf)
+	logger.SetFormatter(&logrus.TextFormatter{DisableColors: true})
+	factory, err := wlog.NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	factory.SetChainLevel("/GET/users/*", logrus.DebugLevel)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		factory.NewBuilder(r.Context()).Leaf().Debug("user loaded")
+	})
+	handler := Middleware(WithFactory(factory), WithFingerPrint(Pattern(mux)))(mux)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	out := buf.String()
+	if !strings.Contains(out, "wlog.fp=\"/GET/users/{id}\"") && !strings.Contains(out, "wlog.fp=/GET/users/{id}") {
+		t.Errorf("the branch should be named by the pattern, got %q", out)
+	}
+	if !strings.Contains(out, "user loaded") {
+		t.Errorf("chain levels should match the pattern branch, got %q", out)
+	}
+}
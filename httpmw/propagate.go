@@ -0,0 +1,37 @@
+package httpmw
+
+import (
+	"net/http"
+
+	"github.com/khicago/wlog"
+)
+
+// transport injects the wlog values of the request context into the request headers
+type transport struct {
+	base http.RoundTripper
+	keys []string
+}
+
+// Transport wraps base, so that the chain, the trace context and the columns of the request context
+// whose keys are whitelisted are sent as request headers, http.DefaultTransport is used when base is nil
+func Transport(base http.RoundTripper, keys ...string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, keys: keys}
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// a RoundTripper should not modify the request
+	r = r.Clone(r.Context())
+	wlog.Inject(r.Context(), r.Header, t.keys...)
+	return t.base.RoundTrip(r)
+}
+
+// Extract restores the chain, the trace context and the columns sent by Transport whose keys are whitelisted
+// into the request context, put it before Middleware, so that the branch of the request continues the chain of the caller
+func Extract(next http.Handler, keys ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(wlog.Extract(r.Context(), r.Header, keys...)))
+	})
+}
@@ -0,0 +1,294 @@
+package wlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// JSONKeys are the key names of the sections written by JSONFormatter, empty names use the defaults
+type JSONKeys struct {
+	Time       string // "ts"
+	Level      string // "level"
+	Message    string // "msg"
+	Chain      string // "chain"
+	ChainNodes string // "chain_nodes"
+	Fields     string // "fields"
+	WLog       string // "wlog"
+}
+
+// defaultJSONKeys are used for the empty names of JSONKeys
+var defaultJSONKeys = JSONKeys{
+	Time:       "ts",
+	Level:      "level",
+	Message:    "msg",
+	Chain:      "chain",
+	ChainNodes: "chain_nodes",
+	Fields:     "fields",
+	WLog:       "wlog",
+}
+
+// JSONFormatter is a logrus.Formatter writing one JSON object per entry, in a stable key order
+//
+//	{"ts":...,"level":...,"msg":...,"chain":"/a/b","chain_nodes":["a","b"],"fields":{...},"wlog":{...}}
+//
+// fields are sorted by key, and the keys used by wlog itself (wlog.src, method_, ...) go into the
+// wlog section without their "wlog." prefix; common value types are encoded without reflection
+type JSONFormatter struct {
+	// TimestampFormat is the format of the time, time.RFC3339Nano by default
+	TimestampFormat string
+	// Keys renames the sections
+	Keys JSONKeys
+}
+
+var _ logrus.Formatter = (*JSONFormatter)(nil)
+
+// Format implements logrus.Formatter
+func (jf *JSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	keys := jf.keys()
+	timestampFormat := jf.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = time.RFC3339Nano
+	}
+
+	var fieldKeys, wlogKeys []string
+	for key := range entry.Data {
+		switch {
+		case key == KeyFingerPrint:
+		case isWLogKey(key):
+			wlogKeys = append(wlogKeys, key)
+		default:
+			fieldKeys = append(fieldKeys, key)
+		}
+	}
+	sort.Strings(fieldKeys)
+	sort.Strings(wlogKeys)
+
+	chain, _ := ChainFromEntry(entry)
+
+	buf := make([]byte, 0, 256)
+	buf = append(buf, '{')
+	buf = appendJSONKey(buf, keys.Time, true)
+	buf = appendJSONString(buf, entry.Time.Format(timestampFormat))
+	buf = appendJSONKey(buf, keys.Level, false)
+	buf = appendJSONString(buf, entry.Level.String())
+	buf = appendJSONKey(buf, keys.Message, false)
+	buf = appendJSONString(buf, entry.Message)
+
+	buf = appendJSONKey(buf, keys.Chain, false)
+	buf = appendJSONString(buf, chain.String())
+	buf = appendJSONKey(buf, keys.ChainNodes, false)
+	buf = append(buf, '[')
+	for i, node := range chain {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendJSONString(buf, node)
+	}
+	buf = append(buf, ']')
+
+	var err error
+	buf = appendJSONKey(buf, keys.Fields, false)
+	buf = append(buf, '{')
+	for i, key := range fieldKeys {
+		buf = appendJSONKey(buf, key, i == 0)
+		if buf, err = appendJSONValue(buf, entry.Data[key]); err != nil {
+			return nil, fmt.Errorf("failed to marshal field %q to JSON, %w", key, err)
+		}
+	}
+	buf = append(buf, '}')
+
+	buf = appendJSONKey(buf, keys.WLog, false)
+	buf = append(buf, '{')
+	first := true
+	for _, key := range wlogKeys {
+		buf = appendJSONKey(buf, wlogKeyName(key), first)
+		if buf, err = appendJSONValue(buf, entry.Data[key]); err != nil {
+			return nil, fmt.Errorf("failed to marshal field %q to JSON, %w", key, err)
+		}
+		first = false
+	}
+	if entry.HasCaller() {
+		buf = appendJSONKey(buf, logrus.FieldKeyFunc, first)
+		buf = appendJSONString(buf, entry.Caller.Function)
+		buf = appendJSONKey(buf, logrus.FieldKeyFile, false)
+		buf = appendJSONString(buf, entry.Caller.File+":"+strconv.Itoa(entry.Caller.Line))
+	}
+	buf = append(buf, '}', '}', '\n')
+
+	if entry.Buffer != nil {
+		entry.Buffer.Write(buf)
+		return entry.Buffer.Bytes(), nil
+	}
+	return buf, nil
+}
+
+func (jf *JSONFormatter) keys() JSONKeys {
+	keys := jf.Keys
+	for _, k := range []struct {
+		name *string
+		def  string
+	}{
+		{&keys.Time, defaultJSONKeys.Time},
+		{&keys.Level, defaultJSONKeys.Level},
+		{&keys.Message, defaultJSONKeys.Message},
+		{&keys.Chain, defaultJSONKeys.Chain},
+		{&keys.ChainNodes, defaultJSONKeys.ChainNodes},
+		{&keys.Fields, defaultJSONKeys.Fields},
+		{&keys.WLog, defaultJSONKeys.WLog},
+	} {
+		if *k.name == "" {
+			*k.name = k.def
+		}
+	}
+	return keys
+}
+
+// isWLogKey reports whether the key is used by wlog itself
+func isWLogKey(key string) bool {
+	return key == KeyMethod || strings.HasPrefix(key, wlogKeyPrefix)
+}
+
+// wlogKeyName is the name of a wlog key in the wlog section
+func wlogKeyName(key string) string {
+	if key == KeyMethod {
+		return "method"
+	}
+	return strings.TrimPrefix(key, wlogKeyPrefix)
+}
+
+// ---- JSON encoding ----
+
+func appendJSONKey(buf []byte, key string, first bool) []byte {
+	if !first {
+		buf = append(buf, ',')
+	}
+	buf = appendJSONString(buf, key)
+	return append(buf, ':')
+}
+
+// appendJSONValue encodes the common types directly, and falls back to encoding/json
+func appendJSONValue(buf []byte, value any) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, "null"...), nil
+	case string:
+		return appendJSONString(buf, v), nil
+	case bool:
+		return strconv.AppendBool(buf, v), nil
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(buf, v, 10), nil
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(buf, v, 10), nil
+	case float32:
+		return appendJSONFloat(buf, float64(v), 32), nil
+	case float64:
+		return appendJSONFloat(buf, v, 64), nil
+	case time.Time:
+		return appendJSONString(buf, v.Format(time.RFC3339Nano)), nil
+	case time.Duration:
+		return appendJSONString(buf, v.String()), nil
+	case Chain:
+		return appendJSONString(buf, v.String()), nil
+	case error:
+		return appendJSONString(buf, v.Error()), nil
+	case *TypedValue:
+		return v.appendJSON(buf)
+	case json.Marshaler:
+		return appendJSONMarshal(buf, v)
+	case fmt.Stringer:
+		return appendJSONString(buf, v.String()), nil
+	default:
+		return appendJSONMarshal(buf, v)
+	}
+}
+
+func appendJSONMarshal(buf []byte, value any) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return buf, err
+	}
+	return append(buf, data...), nil
+}
+
+// appendJSONFloat writes NaN and infinities as strings, since JSON has no literal for them
+func appendJSONFloat(buf []byte, f float64, bitSize int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return appendJSONString(buf, strconv.FormatFloat(f, 'g', -1, bitSize))
+	}
+	return strconv.AppendFloat(buf, f, 'g', -1, bitSize)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString writes s as a JSON string, escaping like encoding/json does without HTML escaping
+func appendJSONString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
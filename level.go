@@ -0,0 +1,109 @@
+package wlog
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// chainWildcard matches any single node of a chain,
+// and the whole subtree when it is the last node of a pattern
+const chainWildcard = "*"
+
+type (
+	// chainLevel overrides the logging level of the chains matching its pattern
+	chainLevel struct {
+		nodes Chain
+		level logrus.Level
+	}
+
+	chainLevels []chainLevel
+)
+
+func newChainLevel(pattern string, level logrus.Level) chainLevel {
+	return chainLevel{nodes: ParseChain(pattern), level: level}
+}
+
+// SetChainLevel overrides the logging level of the chains matching the pattern
+// the pattern is written like a chain, e.g. "/payment/refund", a "*" node matches
+// any single node, and a trailing "*" matches the whole subtree, e.g. "/payment/*"
+// when several patterns match a chain, the most specific one wins
+func (f *Factory) SetChainLevel(pattern string, level logrus.Level) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.chainLevels = f.chainLevels.set(newChainLevel(pattern, level))
+}
+
+// UnsetChainLevel removes the level override of the pattern
+func (f *Factory) UnsetChainLevel(pattern string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.chainLevels = f.chainLevels.unset(ParseChain(pattern).String())
+}
+
+// ChainLevels returns a copy of the level overrides, keyed by pattern
+func (f *Factory) ChainLevels() map[string]logrus.Level {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	levels := make(map[string]logrus.Level, len(f.chainLevels))
+	for _, cl := range f.chainLevels {
+		levels[cl.nodes.String()] = cl.level
+	}
+	return levels
+}
+
+// set replaces the level of the pattern of added, or adds it
+func (cls chainLevels) set(added chainLevel) chainLevels {
+	for i, cl := range cls {
+		if cl.nodes.String() == added.nodes.String() {
+			cls[i].level = added.level
+			return cls
+		}
+	}
+	return append(cls, added)
+}
+
+// unset removes the override of the pattern key
+func (cls chainLevels) unset(key string) chainLevels {
+	for i, cl := range cls {
+		if cl.nodes.String() == key {
+			return append(cls[:i:i], cls[i+1:]...)
+		}
+	}
+	return cls
+}
+
+// levelOf finds the level of the most specific pattern matching the chain
+func (cls chainLevels) levelOf(chain Chain) (logrus.Level, bool) {
+	best, found := -1, false
+	var level logrus.Level
+	for _, cl := range cls {
+		if score, ok := cl.match(chain); ok && score > best {
+			best, level, found = score, cl.level, true
+		}
+	}
+	return level, found
+}
+
+// match reports whether the chain matches the pattern, and how specific the pattern is
+// literal nodes weigh more than wildcards, and an exact length beats a subtree
+func (cl chainLevel) match(chain Chain) (int, bool) {
+	score := 0
+	for i, node := range cl.nodes {
+		if node == chainWildcard && i == len(cl.nodes)-1 {
+			return score, true
+		}
+		if i >= len(chain) {
+			return 0, false
+		}
+		if node == chainWildcard {
+			continue
+		}
+		if node != chain[i] {
+			return 0, false
+		}
+		score += 2
+	}
+	if len(chain) != len(cl.nodes) {
+		return 0, false
+	}
+	return score + 1, true
+}
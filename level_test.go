@@ -0,0 +1,46 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestChainLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	logger.SetLevel(logrus.InfoLevel)
+
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	factory.SetChainLevel("/payment/*", logrus.DebugLevel)
+	factory.SetChainLevel("/payment/poll", logrus.WarnLevel)
+
+	ctx := context.Background()
+	_, payCtx := factory.NewBuilder(ctx).Name("payment").Branch()
+	factory.NewBuilder(payCtx).Name("refund").Leaf().Debug("refund debug")
+	factory.NewBuilder(payCtx).Name("poll").Leaf().Info("poll info")
+	factory.NewBuilder(ctx).Name("order").Leaf().Debug("order debug")
+
+	out := buf.String()
+	if !strings.Contains(out, "refund debug") {
+		t.Errorf("debug of /payment/refund should be printed, got %q", out)
+	}
+	if strings.Contains(out, "poll info") {
+		t.Errorf("info of /payment/poll should be filtered, got %q", out)
+	}
+	if strings.Contains(out, "order debug") {
+		t.Errorf("debug of /order should be filtered, got %q", out)
+	}
+
+	factory.UnsetChainLevel("/payment/*")
+	if levels := factory.ChainLevels(); len(levels) != 1 || levels["/payment/poll"] != logrus.WarnLevel {
+		t.Errorf("unexpected chain levels %v", levels)
+	}
+}
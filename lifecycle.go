@@ -0,0 +1,85 @@
+package wlog
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Flush writes what the Factory instance still holds: the pending summaries of sampling,
+// and the queue of its asynchronous output; then the outputs are synced when they support Sync
+func (f *Factory) Flush(ctx context.Context) error {
+	f.mu.RLock()
+	s, async, sinks, base := f.sampler, f.async, f.sinks, f.defaultEntry
+	f.mu.RUnlock()
+
+	var errs []error
+	if s != nil {
+		errs = append(errs, f.reportDropped(s, false))
+	}
+
+	errs = append(errs, sinks.flush(ctx))
+	if async != nil {
+		errs = append(errs, async.Flush(ctx))
+	} else if base != nil {
+		syncOutput(base.Logger.Out)
+	}
+	return errors.Join(errs...)
+}
+
+// writeDirect writes the entry through the outputs of the factory, bypassing the emission stages
+func (f *Factory) writeDirect(base *logrus.Logger, entry *logrus.Entry) error {
+	serialized, err := f.output(base, entry)
+	if err != nil || len(serialized) == 0 {
+		return err
+	}
+	f.followMu.Lock()
+	defer f.followMu.Unlock()
+	_, err = base.Out.Write(serialized)
+	return err
+}
+
+// Close flushes the Factory instance, and closes its outputs, the logger output and the sinks,
+// except stdout and stderr, closing again does nothing
+// entries logged after Close are not checked, they reach the closed outputs and are lost, unless written to stdout or stderr
+func (f *Factory) Close(ctx context.Context) error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	s, async, sinks, base := f.sampler, f.async, f.sinks, f.defaultEntry
+	f.mu.Unlock()
+
+	if s != nil {
+		s.close() // the pending summaries are written by Flush
+	}
+	err := errors.Join(f.Flush(ctx), sinks.close())
+	switch {
+	case async != nil:
+		err = errors.Join(err, async.Close())
+	case base != nil:
+		err = errors.Join(err, closeOutput(base.Logger.Out))
+	}
+	return err
+}
+
+// Shutdown logs a final LExit entry, then closes every registered factory, the default and the local ones included
+// call it before the process exits, so that buffered outputs are written and files released
+func Shutdown(ctx context.Context) error {
+	LExit.Log("shutdown").Info("wlog is shutting down")
+
+	var errs []error
+	closed := make(map[*Factory]struct{})
+	Range(func(name string, f *Factory) bool {
+		if _, ok := closed[f]; !ok && f != localF {
+			closed[f] = struct{}{}
+			errs = append(errs, f.Close(ctx))
+		}
+		return true
+	})
+	// the local factories are closed last, since closing the others may still log through them
+	return errors.Join(append(errs, localF.Close(ctx), localDiscard.Close(ctx))...)
+}
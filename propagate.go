@@ -0,0 +1,138 @@
+package wlog
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Keys of the carrier which the chain, the columns and the trace context are propagated through
+const (
+	HeaderChain       = "Wlog-Chain"
+	HeaderColumns     = "Wlog-Columns"
+	HeaderTraceparent = "Traceparent"
+)
+
+// Carrier carries the propagated values across process boundaries, http.Header satisfies it
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// Inject writes the chain and the trace context of ctx into the carrier,
+// together with the columns of ctx whose keys are whitelisted, protected as the default factory protects them
+// column values are sent as strings, formatted by fmt.Sprint
+func Inject(ctx context.Context, carrier Carrier, keys ...string) {
+	getDefaultFactory().Inject(ctx, carrier, keys...)
+}
+
+// Inject works like the package level Inject, with the columns protected as the Factory instance protects its entries:
+// the columns it encrypts and the values marked by Sensitive are never sent, and the others are sent redacted
+func (f *Factory) Inject(ctx context.Context, carrier Carrier, keys ...string) {
+	if chain := ChainFromCtx(ctx); len(chain) > 0 {
+		carrier.Set(HeaderChain, encodeChain(chain))
+	}
+
+	if tc, ok := TraceFromCtx(ctx); ok && tc.IsValid() {
+		carrier.Set(HeaderTraceparent, tc.Traceparent())
+	}
+
+	if len(keys) == 0 {
+		return
+	}
+	f.mu.RLock()
+	r, e := f.redactor, f.encryptor
+	f.mu.RUnlock()
+
+	values := url.Values{}
+	for _, col := range ColumnsFromCtx(ctx) {
+		if !slices.Contains(keys, col.Key) {
+			continue
+		}
+		value := col.Any()
+		if _, sensitive := value.(SensitiveValue); sensitive || (e != nil && e.selects(col.Key)) {
+			continue
+		}
+		if r != nil {
+			redacted, _, dropped := r.value(col.Key, value)
+			if dropped {
+				continue
+			}
+			value = redacted
+		}
+		values.Set(col.Key, fmt.Sprint(value))
+	}
+	if len(values) > 0 {
+		carrier.Set(HeaderColumns, values.Encode())
+	}
+}
+
+// Extract restores the chain, the trace context and the columns in the carrier into ctx
+// the propagated chain replaces the chain of ctx, and is cut to maxExtractNodes nodes of at most maxExtractNodeLen bytes,
+// only the propagated columns whose keys are whitelisted, as for Inject, are combined with the columns of ctx
+func Extract(ctx context.Context, carrier Carrier, keys ...string) context.Context {
+	if encoded := carrier.Get(HeaderChain); encoded != "" {
+		ctx = decodeChain(encoded).WriteCtx(ctx)
+	}
+
+	if tc, err := ParseTraceparent(carrier.Get(HeaderTraceparent)); err == nil {
+		ctx = tc.WriteCtx(ctx)
+	}
+
+	if len(keys) == 0 {
+		return ctx
+	}
+	if encoded := carrier.Get(HeaderColumns); encoded != "" {
+		values, err := url.ParseQuery(encoded)
+		if err != nil {
+			return ctx
+		}
+		cols := make(Columns, 0, len(keys))
+		for _, key := range keys {
+			if vs, ok := values[key]; ok && len(vs) > 0 {
+				cols = append(cols, Column{Key: key, Value: vs[0]})
+			}
+		}
+		if len(cols) > 0 {
+			ctx = ColumnsFromCtx(ctx).Combine(cols).WriteCtx(ctx)
+		}
+	}
+	return ctx
+}
+
+// ---- private ----
+
+// bounds of a propagated chain, so that a caller cannot make every entry of the callee arbitrarily large
+const (
+	maxExtractNodes   = 32
+	maxExtractNodeLen = 128
+)
+
+// encodeChain escapes every node, so that nodes containing "/" survive the round trip
+func encodeChain(chain Chain) string {
+	var builder strings.Builder
+	for _, node := range chain {
+		builder.WriteString("/")
+		builder.WriteString(url.PathEscape(node))
+	}
+	return builder.String()
+}
+
+func decodeChain(encoded string) Chain {
+	chain := ParseChain(encoded)
+	if len(chain) > maxExtractNodes {
+		chain = chain[:maxExtractNodes]
+	}
+	for i, node := range chain {
+		if unescaped, err := url.PathUnescape(node); err == nil {
+			node = unescaped
+		}
+		if len(node) > maxExtractNodeLen {
+			node = node[:maxExtractNodeLen]
+		}
+		chain[i] = node
+	}
+	return chain
+}
@@ -0,0 +1,280 @@
+package wlog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RedactAction is what a RedactRule does to the values it matches
+type RedactAction int
+
+const (
+	// RedactMask replaces the value with the mask
+	RedactMask RedactAction = iota
+	// RedactHash replaces the value with its HMAC-SHA256 keyed by the salt, so that equal values can still be correlated
+	RedactHash
+	// RedactDrop removes the column, and the matched text from the message
+	RedactDrop
+	// RedactTruncate keeps the first Keep runes of the value, followed by the mask
+	RedactTruncate
+)
+
+// defaultRedactMask is the mask of Redaction when Mask is not given
+const defaultRedactMask = "***"
+
+// defaultRedactKeep is the number of runes kept by RedactTruncate when Keep is not given
+const defaultRedactKeep = 4
+
+// Patterns of sensitive values, to be used as RedactRule.Value
+// the matches of PatternCreditCard are only redacted when they pass the Luhn check, so that ids and timestamps are kept
+var (
+	PatternEmail       = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	PatternCreditCard  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	PatternJWT         = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	PatternBearerToken = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+type (
+	// Redaction removes sensitive data from the columns and the messages of a Factory, see SetRedaction
+	// the rules apply in order, each one to the result of the previous ones
+	Redaction struct {
+		Rules []RedactRule
+		// Salt keys RedactHash
+		Salt []byte
+		// Mask replaces what is masked, "***" by default
+		Mask string
+	}
+
+	// RedactRule matches columns by key, by value, or by both
+	// - Key matches the key exactly, or as a glob when it contains any of "*?[", e.g. "*_token"
+	// - KeyRegexp matches the key by a regular expression, instead of Key
+	// - Value matches the parts of string, error and fmt.Stringer values to redact, e.g. PatternEmail
+	// without Value, the whole value of the matched keys is redacted,
+	// a rule with Value but no key matcher also applies to the message of the entries
+	RedactRule struct {
+		Key       string
+		KeyRegexp *regexp.Regexp
+		Value     *regexp.Regexp
+		Action    RedactAction
+		// Keep is the number of runes kept by RedactTruncate, 4 by default
+		Keep int
+	}
+
+	// redactor is the compiled Redaction of a Factory
+	redactor struct {
+		Redaction
+		messageRules []RedactRule
+	}
+)
+
+// PIIRules returns rules applying the action to emails, credit card numbers, JWTs and bearer tokens,
+// in the columns and in the messages
+func PIIRules(action RedactAction) []RedactRule {
+	return []RedactRule{
+		{Value: PatternBearerToken, Action: action},
+		{Value: PatternJWT, Action: action},
+		{Value: PatternEmail, Action: action},
+		{Value: PatternCreditCard, Action: action},
+	}
+}
+
+// SetRedaction sets the redaction of the columns and the messages of the Factory instance, nil disables it
+// every entry is redacted when emitted, before the hooks fire, whatever added its fields, e.g. WithField or WithError
+// it returns ErrInvalidRedactRule when a rule matches nothing
+func (f *Factory) SetRedaction(r *Redaction) error {
+	var compiled *redactor
+	if r != nil {
+		compiled = &redactor{Redaction: *r}
+		compiled.Rules = append([]RedactRule(nil), r.Rules...)
+		if compiled.Mask == "" {
+			compiled.Mask = defaultRedactMask
+		}
+		for _, rule := range compiled.Rules {
+			if rule.Key == "" && rule.KeyRegexp == nil && rule.Value == nil {
+				return ErrInvalidRedactRule
+			}
+			if rule.Key == "" && rule.KeyRegexp == nil {
+				compiled.messageRules = append(compiled.messageRules, rule)
+			}
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.redactor = compiled
+	return nil
+}
+
+// entry redacts the fields and the message of the entry being emitted, the fields of wlog are kept,
+// and so are those encrypted by e, which must stay recoverable by Decrypt
+// entry.Data is the copy logrus makes for the emission, which is changed in place
+func (r *redactor) entry(entry *logrus.Entry, e *Encryption) {
+	for key, v := range entry.Data {
+		if isWLogKey(key) {
+			continue
+		}
+		if typed, ok := v.(*TypedValue); ok {
+			v = typed.Any()
+		}
+		if _, sensitive := v.(SensitiveValue); sensitive || (e != nil && e.selects(key)) {
+			continue
+		}
+		value, changed, dropped := r.value(key, v)
+		switch {
+		case dropped:
+			delete(entry.Data, key)
+		case changed:
+			entry.Data[key] = value
+		}
+	}
+	entry.Message = r.message(entry.Message)
+}
+
+// value applies the rules to the value of the key
+func (r *redactor) value(key string, v any) (value any, changed, dropped bool) {
+	for _, rule := range r.Rules {
+		if !rule.matchKey(key) {
+			continue
+		}
+		if rule.Value == nil {
+			if rule.Action == RedactDrop {
+				return nil, false, true
+			}
+			v, changed = r.apply(rule, fmt.Sprint(v)), true
+			continue
+		}
+		s, ok := stringOf(v)
+		if !ok || !rule.found(s) {
+			continue
+		}
+		if rule.Action == RedactDrop {
+			return nil, false, true
+		}
+		v, changed = rule.Value.ReplaceAllStringFunc(s, func(m string) string {
+			if !rule.valid(m) {
+				return m
+			}
+			return r.apply(rule, m)
+		}), true
+	}
+	return v, changed, false
+}
+
+// message applies the rules without key matcher to the message
+func (r *redactor) message(msg string) string {
+	for _, rule := range r.messageRules {
+		msg = rule.Value.ReplaceAllStringFunc(msg, func(m string) string {
+			if !rule.valid(m) {
+				return m
+			}
+			if rule.Action == RedactDrop {
+				return ""
+			}
+			return r.apply(rule, m)
+		})
+	}
+	return msg
+}
+
+// apply redacts s by the action of the rule
+func (r *redactor) apply(rule RedactRule, s string) string {
+	switch rule.Action {
+	case RedactHash:
+		mac := hmac.New(sha256.New, r.Salt)
+		mac.Write([]byte(s))
+		return "hash:" + hex.EncodeToString(mac.Sum(nil)[:8])
+	case RedactTruncate:
+		keep := rule.Keep
+		if keep <= 0 {
+			keep = defaultRedactKeep
+		}
+		if utf8.RuneCountInString(s) <= keep {
+			return s
+		}
+		for i := range s {
+			if keep == 0 {
+				return s[:i] + r.Mask
+			}
+			keep--
+		}
+		return s
+	default:
+		return r.Mask
+	}
+}
+
+// matchKey reports whether the key is matched, rules without key matcher match every key
+func (rule RedactRule) matchKey(key string) bool {
+	switch {
+	case rule.KeyRegexp != nil:
+		return rule.KeyRegexp.MatchString(key)
+	case rule.Key == "":
+		return true
+	default:
+		return matchPattern(rule.Key, key)
+	}
+}
+
+// found reports whether s holds a valid match of the Value of the rule
+func (rule RedactRule) found(s string) bool {
+	for _, m := range rule.Value.FindAllString(s, -1) {
+		if rule.valid(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// valid reports whether m, matched by the Value of the rule, is to be redacted
+func (rule RedactRule) valid(m string) bool {
+	return rule.Value != PatternCreditCard || luhn(m)
+}
+
+// luhn reports whether the digits of s pass the Luhn check, separators are skipped
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		d := int(s[i] - '0')
+		if d < 0 || d > 9 {
+			continue
+		}
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum, double = sum+d, !double
+	}
+	return sum%10 == 0
+}
+
+// matchPattern matches the key exactly, or as a glob when the pattern contains any of "*?["
+func matchPattern(pattern, key string) bool {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return pattern == key
+	}
+	ok, _ := path.Match(pattern, key)
+	return ok
+}
+
+// stringOf returns the text of the values which can be matched by value patterns
+func stringOf(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case error:
+		return v.Error(), true
+	case fmt.Stringer:
+		return v.String(), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,119 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRedaction(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	if err = factory.SetRedaction(&Redaction{Rules: []RedactRule{{Action: RedactMask}}}); err != ErrInvalidRedactRule {
+		t.Errorf("a rule matching nothing should be invalid, got %v", err)
+	}
+	err = factory.SetRedaction(&Redaction{
+		Salt: []byte("salt"),
+		Rules: append([]RedactRule{
+			{Key: "password", Action: RedactDrop},
+			{Key: "*_token", Action: RedactMask},
+			{KeyRegexp: regexp.MustCompile(`^user_(id|name)$`), Action: RedactHash},
+			{Key: "phone", Action: RedactTruncate, Keep: 3},
+		}, PIIRules(RedactMask)...),
+	})
+	if err != nil {
+		t.Fatalf("set redaction failed: %v", err)
+	}
+
+	_, ctx := factory.NewBuilder(context.Background()).Name("auth").
+		Field("password", "hunter2").
+		Field("refresh_token", "abc").
+		Field("user_id", 42).
+		Field("phone", "13800138000").
+		Field("contact", "mail me at alice@example.com").
+		Branch()
+	factory.NewBuilder(ctx).Name("login").Leaf().Info("card 4111 1111 1111 1111 with Bearer abc.def, order 1718000000000000000 created")
+
+	out := buf.String()
+	for _, leaked := range []string{"hunter2", "password", "abc", "42", "13800138000", "alice@example.com", "4111"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("%q should be redacted, got %q", leaked, out)
+		}
+	}
+	for _, expected := range []string{"refresh_token=\"***\"", "user_id=\"hash:", "phone=\"138***\"", "mail me at ***", "card *** with ***", "order 1718000000000000000 created"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("output should contain %q, got %q", expected, out)
+		}
+	}
+	if columns := ColumnsFromCtx(ctx); len(columns) != 5 {
+		t.Errorf("columns in ctx should not be redacted, got %v", columns)
+	}
+}
+
+// captureHook keeps what the hooks see of the entries, as text
+type captureHook struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (h *captureHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *captureHook) Fire(entry *logrus.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, fmt.Sprint(entry.Message, " ", entry.Data))
+	return nil
+}
+
+func (h *captureHook) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return strings.Join(h.entries, "\n")
+}
+
+func TestRedactionAtEmission(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	hook := &captureHook{}
+	logger.AddHook(hook)
+
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	err = factory.SetRedaction(&Redaction{Rules: append([]RedactRule{{Key: "password", Action: RedactDrop}}, PIIRules(RedactMask)...)})
+	if err != nil {
+		t.Fatalf("set redaction failed: %v", err)
+	}
+
+	log := factory.NewBuilder(context.Background()).Name("signup").Leaf()
+	log.WithField("password", "hunter2").
+		WithField("contact", "alice@example.com").
+		WithError(errors.New("no mailbox for bob@example.com")).
+		Error("welcome mail to carol@example.com failed")
+
+	for name, out := range map[string]string{"output": buf.String(), "hooks": hook.String()} {
+		for _, leaked := range []string{"hunter2", "alice@", "bob@", "carol@"} {
+			if strings.Contains(out, leaked) {
+				t.Errorf("%q should be redacted in the %s, got %q", leaked, name, out)
+			}
+		}
+		if !strings.Contains(out, "no mailbox for ***") {
+			t.Errorf("the error should be redacted in the %s, got %q", name, out)
+		}
+	}
+}
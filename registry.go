@@ -0,0 +1,93 @@
+package wlog
+
+import (
+	"sort"
+	"sync"
+)
+
+// Reserved names of the registry, they always resolve to the current factories and cannot be registered
+const (
+	// RegistryNameDefault is the default factory, see Default
+	RegistryNameDefault = "default"
+	// RegistryNameLocal is the factory of the local methods, e.g. LInit
+	RegistryNameLocal = "local"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*Factory)
+
+	// reserved resolves the reserved names when looked up, since the factories behind them can be replaced
+	reserved = map[string]func() *Factory{
+		RegistryNameDefault: getDefaultFactory,
+		RegistryNameLocal:   func() *Factory { return localF },
+	}
+)
+
+// Register registers the factory under the name, so that config reload, AdminHandler and Shutdown can find it
+// it returns ErrFactoryExists when the name is taken or reserved, and ErrNilFactory when f is nil
+func Register(name string, f *Factory) error {
+	if f == nil {
+		return ErrNilFactory
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := reserved[name]; ok {
+		return ErrFactoryExists
+	}
+	if _, ok := registry[name]; ok {
+		return ErrFactoryExists
+	}
+	registry[name] = f
+	return nil
+}
+
+// Unregister removes the factory registered under the name, and returns it
+// the reserved names cannot be removed
+func Unregister(name string) (*Factory, bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	f, ok := registry[name]
+	delete(registry, name)
+	return f, ok
+}
+
+// Get returns the factory registered under the name
+func Get(name string) (*Factory, bool) {
+	if resolve, ok := reserved[name]; ok {
+		return resolve(), true
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	f, ok := registry[name]
+	return f, ok
+}
+
+// Names returns the sorted names of the registered factories, the reserved ones included
+func Names() []string {
+	registryMu.RLock()
+	names := make([]string, 0, len(reserved)+len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	registryMu.RUnlock()
+	for name := range reserved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Range calls fn with every registered factory, sorted by name, until fn returns false
+// the registry is not locked while fn runs, thus fn can register or unregister factories
+func Range(fn func(name string, f *Factory) bool) {
+	for _, name := range Names() {
+		f, ok := Get(name)
+		if !ok {
+			continue // unregistered meanwhile
+		}
+		if !fn(name, f) {
+			return
+		}
+	}
+}
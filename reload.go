@@ -0,0 +1,239 @@
+package wlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/khicago/irr"
+)
+
+// defaultReloadInterval is the polling interval of a Reloader when none is given
+const defaultReloadInterval = 5 * time.Second
+
+// Reloader applies a JSON config file to a Factory whenever the file changes, or the process receives SIGHUP
+// the file is polled, what changed is logged as an LInit entry, and a config failing to load is logged and skipped
+// the first load is applied as a whole, as Config.Apply does, then only the settings changed in the file are applied
+type Reloader struct {
+	factory  *Factory
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	applied *Config
+	modTime time.Time
+	size    int64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewReloader creates a Reloader applying the config file at path to the factory,
+// polling every interval, 5s when interval is not positive
+func NewReloader(f *Factory, path string, interval time.Duration) *Reloader {
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+	return &Reloader{factory: f, path: path, interval: interval}
+}
+
+// Start applies the config file once, then watches it until Stop
+func (r *Reloader) Start() error {
+	if err := r.Reload(); err != nil {
+		return err
+	}
+
+	r.stop, r.done = make(chan struct{}), make(chan struct{})
+	go r.watch()
+	return nil
+}
+
+// Stop stops watching
+func (r *Reloader) Stop() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.stop = nil
+}
+
+// Reload reads and applies the config file now, it does nothing if the config did not change
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return irr.Wrap(err, "failed to stat wlog config")
+	}
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return irr.Wrap(err, "failed to read wlog config")
+	}
+	r.modTime, r.size = info.ModTime(), info.Size()
+
+	cfg := &Config{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(cfg); err != nil {
+		return irr.Wrap(err, "failed to decode wlog config")
+	}
+
+	previous := r.applied
+	if previous == nil {
+		previous = &Config{}
+	}
+	diff := diffConfig(previous, cfg)
+	if len(diff) == 0 {
+		return nil
+	}
+	// only what changed is applied, so that the levels set at runtime and the state of sinks and sampling survive
+	if err = cfg.apply(r.factory, r.applied); err != nil {
+		return err
+	}
+	r.applied = cfg
+
+	LInit.Log("reload").WithField("path", r.path).WithFields(diff).Info("wlog config reloaded")
+	return nil
+}
+
+// watch polls the file and listens to SIGHUP until stopped
+func (r *Reloader) watch() {
+	defer close(r.done)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-hup:
+			r.reportFailure(r.Reload())
+		case <-ticker.C:
+			if r.changed() {
+				r.reportFailure(r.Reload())
+			}
+		}
+	}
+}
+
+// changed reports whether the file changed since it was read
+func (r *Reloader) changed() bool {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !info.ModTime().Equal(r.modTime) || info.Size() != r.size
+}
+
+func (r *Reloader) reportFailure(err error) {
+	if err != nil {
+		LInit.Log("reload").WithField("path", r.path).WithError(err).Error("failed to reload wlog config")
+	}
+}
+
+// diffConfig describes what changed from old to new, as "old -> new" by key
+// only what Config.Apply changes on a running factory is compared, the formatter and output are not
+func diffConfig(old, new *Config) Fields {
+	diff := Fields{}
+	change := func(key string, from, to any) {
+		if f, t := describe(from), describe(to); f != t {
+			diff[key] = f + " -> " + t
+		}
+	}
+
+	change("level", old.Level, new.Level)
+	change("tracing", old.Tracing, new.Tracing)
+	change("dev", old.Dev, new.Dev)
+	change("sampling", old.Sampling, new.Sampling)
+
+	for pattern, level := range old.ChainLevels {
+		change("chain_levels."+pattern, level, new.ChainLevels[pattern])
+	}
+	for pattern, level := range new.ChainLevels {
+		if _, ok := old.ChainLevels[pattern]; !ok {
+			change("chain_levels."+pattern, nil, level)
+		}
+	}
+
+	oldSinks, newSinks := map[string]SinkConfig{}, map[string]SinkConfig{}
+	for _, s := range old.Sinks {
+		oldSinks[s.Name] = s
+	}
+	for _, s := range new.Sinks {
+		newSinks[s.Name] = s
+	}
+	for _, name := range sortedKeys(oldSinks, newSinks) {
+		o, inOld := oldSinks[name]
+		n, inNew := newSinks[name]
+		switch {
+		case !inNew:
+			change("sinks."+name, o, nil)
+		case !inOld:
+			change("sinks."+name, nil, n)
+		default:
+			change("sinks."+name, o, n)
+		}
+	}
+	return diff
+}
+
+// describe prints a config value for the diff, "-" stands for unset
+func describe(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "-"
+	case string:
+		if v == "" {
+			return "-"
+		}
+		return v
+	case *bool:
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprint(*v)
+	case *SamplingConfig:
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%d/%d/%s", v.First, v.Thereafter, v.Tick)
+	case SinkConfig:
+		described := fmt.Sprintf("%s %s %s %s", v.Output, v.Formatter, v.Level, v.ChainPrefix)
+		if v.Rotate != nil {
+			described += fmt.Sprintf(" rotate %d/%s/%d/%t", v.Rotate.MaxSizeMB, v.Rotate.Interval, v.Rotate.MaxBackups, v.Rotate.Compress)
+		}
+		return described
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func sortedKeys(maps ...map[string]SinkConfig) []string {
+	seen := map[string]struct{}{}
+	for _, m := range maps {
+		for k := range m {
+			seen[k] = struct{}{}
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,270 @@
+package wlog
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/khicago/irr"
+)
+
+// rotateTimeFormat is the suffix format of the backups, sortable by name
+const rotateTimeFormat = "20060102T150405.000000000"
+
+// ErrWriterClosed is returned when writing to a closed writer
+var ErrWriterClosed = irr.Error("writer closed")
+
+// RotateOptions defines when a RotatingFile rotates, and how its backups are kept
+type RotateOptions struct {
+	// MaxSize rotates the file before it grows beyond MaxSize bytes, 0 disables size rotation
+	MaxSize int64
+	// Interval rotates the file when it has been written for Interval, 0 disables time rotation
+	Interval time.Duration
+	// MaxBackups is the number of backups kept, the oldest are removed first, 0 keeps all
+	MaxBackups int
+	// Compress gzips the backups in background
+	Compress bool
+}
+
+// RotatingFile is an io.Writer appending to a file, which is rotated by size and/or interval
+// a backup is named after the file and the time of its rotation, e.g. app.log.20240601T120000.000000000(.gz)
+type RotatingFile struct {
+	path string
+	opts RotateOptions
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	closed bool
+
+	// background compression and pruning of backups
+	wg      sync.WaitGroup
+	pruneMu sync.Mutex
+}
+
+// NewRotatingFile opens the file for appending, creating it and its directory if needed
+func NewRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	w := &RotatingFile{path: path, opts: opts}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, irr.Wrap(err, "failed to create log directory")
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewFileFactory creates a Factory writing text entries to a RotatingFile
+func NewFileFactory(path string, opts RotateOptions) (*Factory, error) {
+	out, err := NewRotatingFile(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewFactory(createFileLogger(out))
+}
+
+// Write implements io.Writer, the file is rotated first when p would exceed the limits
+func (w *RotatingFile) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+	if w.file == nil || w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file now
+func (w *RotatingFile) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrWriterClosed
+	}
+	return w.rotate()
+}
+
+// Sync commits the content of the file to stable storage
+func (w *RotatingFile) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed || w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close closes the file, and waits for the backups being compressed
+func (w *RotatingFile) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	return err
+}
+
+// ---- private ----
+
+func (w *RotatingFile) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return irr.Wrap(err, "failed to open log file")
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return irr.Wrap(err, "failed to stat log file")
+	}
+	w.file, w.size, w.opened = file, info.Size(), time.Now()
+	return nil
+}
+
+func (w *RotatingFile) shouldRotate(n int64) bool {
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+n > w.opts.MaxSize {
+		return true
+	}
+	return w.opts.Interval > 0 && time.Since(w.opened) >= w.opts.Interval
+}
+
+// rotate renames the current file to a backup and opens a new one, w.mu must be held
+// w.file is nil when no file could be opened, the next rotation only retries to open it
+func (w *RotatingFile) rotate() error {
+	if w.file == nil {
+		return w.open()
+	}
+	if err := w.file.Close(); err != nil {
+		return irr.Wrap(err, "failed to close log file")
+	}
+	w.file = nil
+
+	backup := w.backupName(time.Now())
+	if err := os.Rename(w.path, backup); err != nil {
+		// keeps writing to the path, which is created again when it was removed
+		_ = w.open()
+		return irr.Wrap(err, "failed to rename log file")
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.pruneMu.Lock()
+		defer w.pruneMu.Unlock()
+		if w.opts.Compress {
+			_ = compressFile(backup)
+		}
+		w.prune()
+	}()
+	return nil
+}
+
+// backupName makes a name of backup which is not taken yet
+func (w *RotatingFile) backupName(t time.Time) string {
+	name := w.path + "." + t.Format(rotateTimeFormat)
+	for i := 2; exists(name) || exists(name+".gz"); i++ {
+		name = w.path + "." + t.Format(rotateTimeFormat) + "-" + strconv.Itoa(i)
+	}
+	return name
+}
+
+// prune removes the oldest backups beyond MaxBackups
+func (w *RotatingFile) prune() {
+	if w.opts.MaxBackups <= 0 {
+		return
+	}
+	dir, base := filepath.Split(w.path)
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return
+	}
+	var backups []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isBackup(entry.Name(), base) {
+			backups = append(backups, filepath.Join(dir, entry.Name()))
+		}
+	}
+	if len(backups) <= w.opts.MaxBackups {
+		return
+	}
+	sort.Strings(backups)
+	for _, backup := range backups[:len(backups)-w.opts.MaxBackups] {
+		_ = os.Remove(backup)
+	}
+}
+
+// isBackup tells whether name is a backup of the file base, as made by backupName and compressFile
+func isBackup(name, base string) bool {
+	suffix, ok := strings.CutPrefix(name, base+".")
+	if !ok {
+		return false
+	}
+	suffix = strings.TrimSuffix(suffix, ".gz")
+	if i := strings.LastIndexByte(suffix, '-'); i >= 0 {
+		if _, err := strconv.Atoi(suffix[i+1:]); err != nil {
+			return false
+		}
+		suffix = suffix[:i]
+	}
+	_, err := time.Parse(rotateTimeFormat, suffix)
+	return err == nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// compressFile gzips the file into file.gz, and removes the file
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,112 @@
+package wlog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := NewRotatingFile(path, RotateOptions{MaxSize: 16, MaxBackups: 1, Compress: true})
+	if err != nil {
+		t.Fatalf("open rotating file failed: %v", err)
+	}
+
+	for _, line := range []string{"0123456789\n", "abcdefghij\n", "ABCDEFGHIJ\n", "last\n"} {
+		if _, err = w.Write([]byte(line)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != "ABCDEFGHIJ\nlast\n" {
+		t.Errorf("current file should hold the lines after the last rotation, got %q, %v", content, err)
+	}
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 1 {
+		t.Fatalf("expect 1 backup kept, got %v", backups)
+	}
+	for _, backup := range backups {
+		if !strings.HasSuffix(backup, ".gz") {
+			t.Errorf("backup should be compressed, got %s", backup)
+		}
+	}
+}
+
+func TestRotatingFilePrune(t *testing.T) {
+	// glob metacharacters in the path must not stop pruning
+	dir := filepath.Join(t.TempDir(), "logs[1]")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatalf("create dir failed: %v", err)
+	}
+	path := filepath.Join(dir, "app.log")
+	unrelated := []string{"app.log.bak", "app.log.1", "app.log.20240601.gz"}
+	for _, name := range unrelated {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatalf("create file failed: %v", err)
+		}
+	}
+
+	w, err := NewRotatingFile(path, RotateOptions{MaxBackups: 1})
+	if err != nil {
+		t.Fatalf("open rotating file failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = w.Rotate(); err != nil {
+			t.Fatalf("rotate failed: %v", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var backups []string
+	for _, entry := range entries {
+		if isBackup(entry.Name(), "app.log") {
+			backups = append(backups, entry.Name())
+		}
+	}
+	if len(backups) != 1 {
+		t.Errorf("expect 1 backup kept, got %v", backups)
+	}
+	for _, name := range unrelated {
+		if _, err = os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("unrelated file %s should survive pruning: %v", name, err)
+		}
+	}
+}
+
+func TestRotatingFileRemoved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotatingFile(path, RotateOptions{MaxSize: 16})
+	if err != nil {
+		t.Fatalf("open rotating file failed: %v", err)
+	}
+	defer w.Close()
+
+	if _, err = w.Write([]byte("0123456789abcde\n")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err = os.Remove(path); err != nil {
+		t.Fatalf("remove file failed: %v", err)
+	}
+	if _, err = w.Write([]byte("lost\n")); err == nil {
+		t.Error("rotating a removed file should fail")
+	}
+	for _, line := range []string{"after\n", "more\n"} {
+		if _, err = w.Write([]byte(line)); err != nil {
+			t.Fatalf("writes should go on after a failed rotation, got %v", err)
+		}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != "after\nmore\n" {
+		t.Errorf("the file should be created again, got %q, %v", content, err)
+	}
+}
@@ -0,0 +1,254 @@
+package wlog
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// followerKey identifies a logger derived from base at the given level
+type followerKey struct {
+	base  *logrus.Logger
+	level logrus.Level
+}
+
+// route attaches the entry to a follower logger of the factory when needed
+// followers filter at the level of the chain, and run the emission stages of the factory
+// entries without any of them keep the logger they were created with
+func (f *Factory) route(ctx context.Context, entry *logrus.Entry, chain Chain) *logrus.Entry {
+	if entry.Logger == nil {
+		return entry
+	}
+
+	level := f.levelOf(entry.Logger, chain)
+	f.mu.RLock()
+	staged := f.staged()
+	f.mu.RUnlock()
+
+	if tailFromCtx(ctx) != nil {
+		// entries filtered out by level are captured by the tail scope at emission
+		staged = true
+		level = logrus.TraceLevel
+		entry.Context = ctx
+	}
+	if len(entry.Logger.Hooks) > 0 {
+		// typed values are boxed in the emission stages before hooks fire
+		staged = true
+	}
+	if !staged && level == entry.Logger.GetLevel() {
+		return entry
+	}
+	entry.Logger = f.follower(entry.Logger, level)
+	return entry
+}
+
+// levelOf returns the level of the chain, which is the level of base unless overridden
+func (f *Factory) levelOf(base *logrus.Logger, chain Chain) logrus.Level {
+	f.mu.RLock()
+	level, ok := f.chainLevels.levelOf(chain)
+	f.mu.RUnlock()
+	if !ok {
+		return base.GetLevel()
+	}
+	return level
+}
+
+// staged reports whether the factory has any emission stage, f.mu must be held
+func (f *Factory) staged() bool {
+	return f.sampler != nil || f.async != nil || f.redactor != nil || f.encryptor != nil || len(f.sinks) > 0
+}
+
+// follower returns the cached logger derived from base at the given level
+func (f *Factory) follower(base *logrus.Logger, level logrus.Level) *logrus.Logger {
+	key := followerKey{base: base, level: level}
+
+	f.mu.RLock()
+	logger, ok := f.followers[key]
+	f.mu.RUnlock()
+	if ok {
+		return logger
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if logger, ok = f.followers[key]; ok {
+		return logger
+	}
+	if f.followers == nil {
+		f.followers = make(map[followerKey]*logrus.Logger)
+	}
+	state := &followState{emits: make(map[*logrus.Entry][]*logrus.Entry)}
+	hooks := make(logrus.LevelHooks)
+	hooks.Add(stageHook{base: base, factory: f, state: state})
+	logger = &logrus.Logger{
+		Out:          &followWriter{base: base, mu: &f.followMu, state: state},
+		Hooks:        hooks,
+		Formatter:    followFormatter{base: base, factory: f, state: state},
+		ReportCaller: base.ReportCaller,
+		Level:        level,
+		ExitFunc:     base.ExitFunc,
+		BufferPool:   base.BufferPool,
+	}
+	f.followers[key] = logger
+	return logger
+}
+
+// stage runs the emission stages of the factory, and returns the entries to emit in place of entry
+// it returns none when the entry is suppressed
+func (f *Factory) stage(base *logrus.Logger, entry *logrus.Entry) []*logrus.Entry {
+	f.mu.RLock()
+	s, r, e := f.sampler, f.redactor, f.encryptor
+	f.mu.RUnlock()
+
+	if r != nil {
+		r.entry(entry, e)
+	}
+	if e != nil {
+		e.entry(entry)
+	}
+	if len(base.Hooks) > 0 {
+		// hooks of other packages switch on the types of the values, which they would not know
+		boxFields(entry.Data)
+	}
+
+	var emits []*logrus.Entry
+	if scope := tailFromCtx(entry.Context); scope != nil {
+		chain, _ := ChainFromEntry(entry)
+		if entry.Level > f.levelOf(base, chain) {
+			if !scope.hold(entry) {
+				return nil
+			}
+		} else if entry.Level <= logrus.ErrorLevel {
+			emits = append(emits, scope.fail()...)
+		}
+	}
+
+	pass := true
+	if s != nil {
+		var dropped int
+		pass, dropped = s.admit(base, entry)
+		if dropped > 0 {
+			emits = append(emits, s.summary(entry, dropped))
+		}
+	}
+	if pass {
+		emits = append(emits, entry)
+	}
+	return emits
+}
+
+// output writes the entries to the sinks of the factory if there are any,
+// otherwise it formats the entries with base, to be written to the output of base
+func (f *Factory) output(base *logrus.Logger, entries ...*logrus.Entry) ([]byte, error) {
+	f.mu.RLock()
+	sinks := f.sinks
+	f.mu.RUnlock()
+
+	if len(sinks) > 0 {
+		for _, entry := range entries {
+			sinks.write(base, entry)
+		}
+		return nil, nil
+	}
+
+	if len(entries) == 1 {
+		return base.Formatter.Format(entries[0])
+	}
+	var out []byte
+	for _, entry := range entries {
+		serialized, err := base.Formatter.Format(entry)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, serialized...)
+	}
+	return out, nil
+}
+
+// ----- follower plumbing -----
+
+// followState passes the entries to emit from the stage hook to the formatter of a follower,
+// and the level of the entry being written from the formatter to the writer
+// logrus holds the lock of the follower from formatting to writing, which guards the level
+type followState struct {
+	level logrus.Level
+
+	mu    sync.Mutex
+	emits map[*logrus.Entry][]*logrus.Entry
+}
+
+func (s *followState) put(entry *logrus.Entry, emits []*logrus.Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.emits[entry] = emits
+}
+
+func (s *followState) take(entry *logrus.Entry) ([]*logrus.Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	emits, ok := s.emits[entry]
+	delete(s.emits, entry)
+	return emits, ok
+}
+
+// stageHook is the only hook of a follower, logrus fires it before formatting
+// it runs the emission stages, then fires the hooks of the base logger for the entries to emit only
+type stageHook struct {
+	base    *logrus.Logger
+	factory *Factory
+	state   *followState
+}
+
+func (h stageHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h stageHook) Fire(entry *logrus.Entry) error {
+	emits := h.factory.stage(h.base, entry)
+	h.state.put(entry, emits)
+
+	var errs []error
+	for _, emit := range emits {
+		errs = append(errs, h.base.Hooks.Fire(emit.Level, emit))
+	}
+	return errors.Join(errs...)
+}
+
+// followWriter writes through the current output of the base logger
+// writes from all followers of a factory are serialized by mu
+type followWriter struct {
+	base  *logrus.Logger
+	mu    *sync.Mutex
+	state *followState
+}
+
+func (w *followWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if lw, ok := w.base.Out.(LevelWriter); ok {
+		return lw.WriteLevel(w.state.level, p)
+	}
+	return w.base.Out.Write(p)
+}
+
+// followFormatter formats the entries to emit with the current formatter of the base logger
+type followFormatter struct {
+	base    *logrus.Logger
+	factory *Factory
+	state   *followState
+}
+
+func (ff followFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	ff.state.level = entry.Level
+	emits, ok := ff.state.take(entry)
+	if !ok {
+		// not fired through the stage hook, e.g. formatted outside of logrus
+		emits = []*logrus.Entry{entry}
+	}
+	return ff.factory.output(ff.base, emits...)
+}
@@ -0,0 +1,203 @@
+package wlog
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultSamplingTick is the window of Sampling when Tick is not given
+const defaultSamplingTick = time.Second
+
+// Sampling limits the entries logged under each chain and level
+// in every Tick, the First entries pass, and then 1 in Thereafter of the rest passes
+// the number of dropped entries is reported by a summary entry under the same chain, written once the
+// window is over, every Tick, or before the first entry of the next window when it comes earlier
+// dropped entries are decided before the hooks of the logger fire, and are not formatted
+type Sampling struct {
+	First      int
+	Thereafter int // 0 drops all the entries after First
+	Tick       time.Duration
+}
+
+type (
+	samplerKey struct {
+		chain string
+		level logrus.Level
+	}
+
+	sampleWindow struct {
+		base    *logrus.Logger
+		chain   Chain
+		start   time.Time
+		count   int
+		dropped int
+	}
+
+	// sampler is the emission stage applying Sampling
+	// once started, it reports the windows which are over every Tick, and evicts them
+	sampler struct {
+		Sampling
+		mu      sync.Mutex
+		windows map[samplerKey]*sampleWindow
+		stop    chan struct{}
+		done    chan struct{}
+		once    sync.Once
+	}
+)
+
+// SetSampling sets the sampling of the Factory instance, nil disables sampling
+// what the previous sampling has dropped is reported before it is replaced
+func (f *Factory) SetSampling(sampling *Sampling) {
+	var s *sampler
+	if sampling != nil {
+		s = newSampler(*sampling)
+	}
+	f.swapSampler(s)
+}
+
+// swapSampler replaces the sampler of the factory, starts the new one, and retires the previous one
+func (f *Factory) swapSampler(s *sampler) {
+	f.mu.Lock()
+	previous := f.sampler
+	f.sampler = s
+	f.mu.Unlock()
+
+	if s != nil {
+		s.start(f)
+	}
+	f.retireSampler(previous)
+}
+
+// retireSampler stops the sampler, and reports what it has dropped
+func (f *Factory) retireSampler(s *sampler) {
+	if s == nil {
+		return
+	}
+	s.close()
+	_ = f.reportDropped(s, false)
+}
+
+// reportDropped writes the summaries of the windows of s which have dropped entries, bypassing the emission stages
+// when expired is set, only the windows which are over are reported, and they are evicted
+func (f *Factory) reportDropped(s *sampler, expired bool) error {
+	var errs []error
+	now := time.Now()
+	s.drain(now, expired, func(w *sampleWindow, level logrus.Level) {
+		summary := logrus.NewEntry(w.base).WithField(KeySampleDropped, w.dropped)
+		summary = w.chain.WriteEntry(summary)
+		summary.Time, summary.Level, summary.Message = now, level, sampleSummaryMessage
+		errs = append(errs, f.writeDirect(w.base, summary))
+	})
+	return errors.Join(errs...)
+}
+
+// sampleSummaryMessage is the message of the entries reporting the dropped entries
+const sampleSummaryMessage = "entries dropped by sampling"
+
+func newSampler(sampling Sampling) *sampler {
+	if sampling.Tick <= 0 {
+		sampling.Tick = defaultSamplingTick
+	}
+	return &sampler{
+		Sampling: sampling,
+		windows:  make(map[samplerKey]*sampleWindow),
+	}
+}
+
+// start reports and evicts the windows which are over every Tick, until close
+func (s *sampler) start(f *Factory) {
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.Tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				_ = f.reportDropped(s, true)
+			}
+		}
+	}()
+}
+
+// close stops the reporting started by start
+func (s *sampler) close() {
+	s.once.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+			<-s.done
+		}
+	})
+}
+
+// admit reports whether the entry passes, and the number of entries dropped
+// in the previous window of its chain and level, which is to be reported
+func (s *sampler) admit(base *logrus.Logger, entry *logrus.Entry) (bool, int) {
+	chain, _ := ChainFromEntry(entry)
+	key := samplerKey{chain: chain.String(), level: entry.Level}
+	now := entry.Time
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.windows[key]
+	if !ok {
+		w = &sampleWindow{base: base, chain: chain, start: now}
+		s.windows[key] = w
+	}
+
+	reported := 0
+	if now.Sub(w.start) >= s.Tick {
+		reported = w.dropped
+		w.start, w.count, w.dropped = now, 0, 0
+	}
+
+	w.count++
+	if w.count <= s.First {
+		return true, reported
+	}
+	if s.Thereafter > 0 && (w.count-s.First)%s.Thereafter == 0 {
+		return true, reported
+	}
+	w.dropped++
+	return false, reported
+}
+
+// drain calls report for every window which has dropped entries, and resets their dropped counts
+// when expired is set, only the windows which are over at now are drained, and all of them are evicted
+func (s *sampler) drain(now time.Time, expired bool, report func(w *sampleWindow, level logrus.Level)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, w := range s.windows {
+		over := now.Sub(w.start) >= s.Tick
+		if expired && !over {
+			continue
+		}
+		if w.dropped > 0 {
+			report(w, key.level)
+			w.dropped = 0
+		}
+		if expired {
+			delete(s.windows, key)
+		}
+	}
+}
+
+// summary makes the entry reporting the dropped entries of the chain and level of entry
+func (s *sampler) summary(entry *logrus.Entry, dropped int) *logrus.Entry {
+	summary := logrus.NewEntry(entry.Logger)
+	summary.Data[KeySampleDropped] = dropped
+	if chain, ok := ChainFromEntry(entry); ok {
+		summary = chain.WriteEntry(summary)
+	}
+	summary.Context = entry.Context
+	summary.Time = entry.Time
+	summary.Level = entry.Level
+	summary.Message = sampleSummaryMessage
+	return summary
+}
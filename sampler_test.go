@@ -0,0 +1,147 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSampling(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	factory.SetSampling(&Sampling{First: 2, Thereafter: 3, Tick: time.Second})
+
+	log := factory.NewBuilder(context.Background()).Name("poll").Leaf()
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		log.WithTime(start).Info("poll")
+	}
+	if n := strings.Count(buf.String(), "msg=poll"); n != 4 {
+		t.Errorf("expect 2 first entries and 2 sampled, got %d\n%s", n, buf.String())
+	}
+
+	buf.Reset()
+	log.WithTime(start.Add(time.Second)).Info("poll")
+	out := buf.String()
+	if !strings.Contains(out, KeySampleDropped+"=6") || !strings.Contains(out, KeyFingerPrint+"=/poll") {
+		t.Errorf("expect summary of 6 dropped entries under /poll, got %q", out)
+	}
+}
+
+func TestFlushSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	factory, err := NewFileFactory(path, RotateOptions{})
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	factory.SetSampling(&Sampling{First: 1})
+
+	log := factory.NewBuilder(context.Background()).Name("poll").Leaf()
+	for i := 0; i < 5; i++ {
+		log.Info("poll")
+	}
+	if err = factory.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log file failed: %v", err)
+	}
+	if !strings.Contains(string(content), KeySampleDropped+"=4") {
+		t.Errorf("pending summary should be written on close, got %q", content)
+	}
+}
+
+func TestSamplingTicker(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+	hook := &countHook{}
+	logger.AddHook(hook)
+
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	factory.SetSampling(&Sampling{First: 1, Tick: 20 * time.Millisecond})
+	defer factory.SetSampling(nil)
+
+	log := factory.NewBuilder(context.Background()).Name("burst").Leaf()
+	for i := 0; i < 5; i++ {
+		log.Info("burst")
+	}
+	if n := hook.count(); n != 1 {
+		t.Errorf("hooks should fire for sampled entries only, got %d", n)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(buf.String(), KeySampleDropped+"=4") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), KeySampleDropped+"=4") {
+		t.Errorf("summary of a burst should be written after the tick, got %q", buf.String())
+	}
+
+	factory.mu.RLock()
+	s := factory.sampler
+	factory.mu.RUnlock()
+	s.mu.Lock()
+	windows := len(s.windows)
+	s.mu.Unlock()
+	if windows != 0 {
+		t.Errorf("windows which are over should be evicted, got %d", windows)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the writes of the sampling ticker
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// countHook counts the entries it is fired for
+type countHook struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (h *countHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *countHook) Fire(*logrus.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.n++
+	return nil
+}
+
+func (h *countHook) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.n
+}
@@ -0,0 +1,177 @@
+package wlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// Sink is a named output of a Factory, once a factory has sinks, entries are written to
+	// every sink accepting them, instead of the output of the logger of the factory
+	Sink struct {
+		Name string
+		// Out is written synchronously, SetAsync does not cover sinks, make Out an AsyncWriter to write in background
+		Out io.Writer
+		// Formatter formats the entries of the sink, the formatter of the logger is used when nil
+		Formatter logrus.Formatter
+		// Level is the least severe level written to the sink, e.g. logrus.InfoLevel
+		// the sink never sees what the level of the factory or of the chain filters out
+		Level logrus.Level
+		// ChainPrefix only keeps the entries under the chain, e.g. "/payment", empty keeps all
+		ChainPrefix string
+	}
+
+	// SinkStats reports a sink and the number of entries written to it
+	SinkStats struct {
+		Name        string       `json:"name"`
+		Level       logrus.Level `json:"level"`
+		ChainPrefix string       `json:"chain_prefix"`
+		Written     uint64       `json:"written"`
+		Failed      uint64       `json:"failed"`
+	}
+
+	sink struct {
+		Sink
+		prefix Chain
+		// config declares the sink when it is opened by Config.Apply
+		config  *SinkConfig
+		mu      sync.Mutex
+		written atomic.Uint64
+		failed  atomic.Uint64
+	}
+
+	sinks []*sink
+)
+
+// AddSink adds a sink to the Factory instance, the name of the sink must be unique
+func (f *Factory) AddSink(s Sink) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, existing := range f.sinks {
+		if existing.Name == s.Name {
+			return ErrSinkExists
+		}
+	}
+	// copy on write, so that the sinks can be read without lock once loaded
+	added := make(sinks, 0, len(f.sinks)+1)
+	added = append(added, f.sinks...)
+	f.sinks = append(added, &sink{Sink: s, prefix: ParseChain(s.ChainPrefix)})
+	return nil
+}
+
+// RemoveSink removes the sink of the name from the Factory instance, and returns it
+// its output is not closed, since the caller gets it back
+func (f *Factory) RemoveSink(name string) (Sink, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, s := range f.sinks {
+		if s.Name == name {
+			removed := make(sinks, 0, len(f.sinks)-1)
+			removed = append(removed, f.sinks[:i]...)
+			f.sinks = append(removed, f.sinks[i+1:]...)
+			return s.Sink, true
+		}
+	}
+	return Sink{}, false
+}
+
+// Sinks returns the stats of the sinks of the Factory instance
+func (f *Factory) Sinks() []SinkStats {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	stats := make([]SinkStats, 0, len(f.sinks))
+	for _, s := range f.sinks {
+		stats = append(stats, SinkStats{
+			Name:        s.Name,
+			Level:       s.Level,
+			ChainPrefix: s.prefix.String(),
+			Written:     s.written.Load(),
+			Failed:      s.failed.Load(),
+		})
+	}
+	return stats
+}
+
+// write writes the entry to every sink accepting it
+func (ss sinks) write(base *logrus.Logger, entry *logrus.Entry) {
+	chain, _ := ChainFromEntry(entry)
+	for _, s := range ss {
+		if entry.Level > s.Level || !chain.HasPrefix(s.prefix) {
+			continue
+		}
+		if err := s.write(base, entry); err != nil {
+			s.failed.Add(1)
+			continue
+		}
+		s.written.Add(1)
+	}
+}
+
+func (s *sink) write(base *logrus.Logger, entry *logrus.Entry) error {
+	formatter := s.Formatter
+	if formatter == nil {
+		formatter = base.Formatter
+	}
+
+	// formatters append to the buffer of the entry, which must not be shared between sinks
+	e := *entry
+	e.Buffer = nil
+	serialized, err := formatter.Format(&e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lw, ok := s.Out.(LevelWriter); ok {
+		_, err = lw.WriteLevel(entry.Level, serialized)
+	} else {
+		_, err = s.Out.Write(serialized)
+	}
+	return err
+}
+
+// flush waits for the sinks writing through an AsyncWriter, and syncs the outputs of the others
+func (ss sinks) flush(ctx context.Context) error {
+	var errs []error
+	for _, s := range ss {
+		if async, ok := s.Out.(*AsyncWriter); ok {
+			errs = append(errs, async.Flush(ctx))
+			continue
+		}
+		s.mu.Lock()
+		syncOutput(s.Out)
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// without returns the sinks which are not in others
+func (ss sinks) without(others sinks) sinks {
+	var left sinks
+	for _, s := range ss {
+		if !slices.Contains(others, s) {
+			left = append(left, s)
+		}
+	}
+	return left
+}
+
+// close closes the outputs of the sinks, except stdout and stderr
+func (ss sinks) close() error {
+	var errs []error
+	for _, s := range ss {
+		s.mu.Lock()
+		if err := closeOutput(s.Out); err != nil {
+			errs = append(errs, err)
+		}
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
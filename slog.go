@@ -0,0 +1,132 @@
+package wlog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SlogHandler is a slog.Handler which logs through a Factory
+// groups are mapped to nodes of the chain, and attrs are mapped to columns
+type SlogHandler struct {
+	factory *Factory
+	chain   Chain
+	columns Columns
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
+
+// NewSlogHandler creates a slog.Handler backed by the factory, e.g. slog.New(wlog.NewSlogHandler(f))
+func NewSlogHandler(f *Factory) *SlogHandler {
+	return &SlogHandler{factory: f}
+}
+
+// Enabled reports whether the factory logs records of the level, under the chain of ctx and the groups
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return h.factory.enabled(ctx, ChainFromCtx(ctx).Join(h.chain), levelFromSlog(level))
+}
+
+// Handle logs the record, the chain and columns cached in ctx are used as the context of the builder
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	builder := h.factory.NewBuilder(ctx).Name(h.chain...)
+	builder.Columns(h.columns...)
+	record.Attrs(func(attr slog.Attr) bool {
+		builder.Columns(attrColumns("", attr)...)
+		return true
+	})
+
+	entry := builder.Leaf().Entry
+	if !record.Time.IsZero() {
+		entry = entry.WithTime(record.Time)
+	}
+	entry.Log(levelFromSlog(record.Level), record.Message)
+	return nil
+}
+
+// WithAttrs returns a handler whose entries carry the attrs as columns
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	columns := Columns(nil).Combine(h.columns)
+	for _, attr := range attrs {
+		columns = columns.Set(attrColumns("", attr)...)
+	}
+	columns.box()
+	return &SlogHandler{factory: h.factory, chain: h.chain, columns: columns}
+}
+
+// WithGroup returns a handler whose chain is extended by the name of the group
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{factory: h.factory, chain: Chain{}.Join(h.chain).Join(Chain{name}), columns: h.columns}
+}
+
+// ---- private ----
+
+// attrColumns converts an attr to columns, the attrs of group values are flattened as "group.key"
+func attrColumns(prefix string, attr slog.Attr) Columns {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return nil
+	}
+
+	key := attr.Key
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+	case slog.KindString:
+		return Columns{Str(key, attr.Value.String())}
+	case slog.KindInt64:
+		return Columns{Int64(key, attr.Value.Int64())}
+	case slog.KindUint64:
+		return Columns{Uint64(key, attr.Value.Uint64())}
+	case slog.KindFloat64:
+		return Columns{Float64(key, attr.Value.Float64())}
+	case slog.KindBool:
+		return Columns{Bool(key, attr.Value.Bool())}
+	case slog.KindDuration:
+		return Columns{Dur(key, attr.Value.Duration())}
+	case slog.KindTime:
+		return Columns{Time(key, attr.Value.Time())}
+	default:
+		return Columns{{Key: key, Value: attr.Value.Any()}}
+	}
+
+	if attr.Key == "" {
+		// inline the attrs of groups without key
+		key = prefix
+	}
+	var columns Columns
+	for _, member := range attr.Value.Group() {
+		columns = append(columns, attrColumns(key, member)...)
+	}
+	return columns
+}
+
+// levelFromSlog maps a slog level to the nearest logrus level
+func levelFromSlog(level slog.Level) logrus.Level {
+	switch {
+	case level >= slog.LevelError:
+		return logrus.ErrorLevel
+	case level >= slog.LevelWarn:
+		return logrus.WarnLevel
+	case level >= slog.LevelInfo:
+		return logrus.InfoLevel
+	case level >= slog.LevelDebug:
+		return logrus.DebugLevel
+	default:
+		return logrus.TraceLevel
+	}
+}
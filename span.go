@@ -0,0 +1,61 @@
+package wlog
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CtxKeySpan is the key to cache the span into a context
+var CtxKeySpan = struct{ CtxKeySpan struct{} }{}
+
+// Outcome values of an ended span
+const (
+	SpanOutcomeOK    = "ok"
+	SpanOutcomeError = "error"
+)
+
+// span records when a branch started
+type span struct {
+	start  time.Time
+	parent Chain
+}
+
+// SpanEnd ends a span, err is the outcome of the span, nil means ok
+type SpanEnd func(err error)
+
+// Span works like Branch, and records the start time of the branch in the returned context
+// calling the returned SpanEnd logs a completion entry with the duration, the parent chain and the outcome
+// it is logged at Info level, or at Error level with the error when err is not nil
+func (b *Builder) Span() (WLog, context.Context, SpanEnd) {
+	factory, parent := b.factory, ChainFromCtx(b.ctx)
+	sp := &span{start: time.Now(), parent: parent}
+	b.ctx = context.WithValue(b.ctx, CtxKeySpan, sp)
+	log, ctx := b.Branch()
+
+	var once sync.Once
+	return log, ctx, func(err error) {
+		once.Do(func() { sp.end(factory, ctx, err) })
+	}
+}
+
+// SpanStartFromCtx get the start time of the innermost span from context
+func SpanStartFromCtx(ctx context.Context) (time.Time, bool) {
+	if sp, ok := ctx.Value(CtxKeySpan).(*span); ok {
+		return sp.start, true
+	}
+	return time.Time{}, false
+}
+
+// end logs the completion entry of the span
+func (sp *span) end(factory *Factory, ctx context.Context, err error) {
+	elapsed := time.Since(sp.start)
+	builder := factory.NewBuilder(ctx).
+		Field(KeySpanDuration, float64(elapsed.Microseconds())/1000).
+		Field(KeySpanParent, sp.parent.String())
+	if err != nil {
+		builder.Field(KeySpanOutcome, SpanOutcomeError).Leaf().WithError(err).Error("span ended")
+		return
+	}
+	builder.Field(KeySpanOutcome, SpanOutcomeOK).Leaf().Info("span ended")
+}
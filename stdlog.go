@@ -0,0 +1,105 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StdWriter is an io.Writer which logs every line written to it through a Factory, under a fixed chain
+// the level of a line is parsed from its prefix, such as "[ERROR] ", "WARN: " or "debug ",
+// lines without a known prefix are logged at the default level, Info unless changed
+// fatal and panic lines are logged at Fatal level, without exiting or panicking
+type StdWriter struct {
+	factory      *Factory
+	chain        Chain
+	defaultLevel logrus.Level
+}
+
+// levelPrefixes are the known level names, matched case-insensitively
+var levelPrefixes = []struct {
+	name  string
+	level logrus.Level
+}{
+	{"trace", logrus.TraceLevel},
+	{"debug", logrus.DebugLevel},
+	{"info", logrus.InfoLevel},
+	{"warning", logrus.WarnLevel},
+	{"warn", logrus.WarnLevel},
+	{"error", logrus.ErrorLevel},
+	{"err", logrus.ErrorLevel},
+	{"fatal", logrus.FatalLevel},
+	{"panic", logrus.FatalLevel},
+}
+
+// NewStdWriter creates a StdWriter logging through the factory under the chain
+func NewStdWriter(f *Factory, chain ...string) *StdWriter {
+	return &StdWriter{factory: f, chain: chain, defaultLevel: logrus.InfoLevel}
+}
+
+// NewStdLogger creates a *log.Logger logging through the factory under the chain
+// for libraries which accept a *log.Logger
+func NewStdLogger(f *Factory, chain ...string) *log.Logger {
+	return log.New(NewStdWriter(f, chain...), "", 0)
+}
+
+// RedirectStdLog makes the standard logger of package log write through the factory under the chain
+// it returns a function restoring the previous output and flags of the standard logger
+func RedirectStdLog(f *Factory, chain ...string) func() {
+	out, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+	log.SetOutput(NewStdWriter(f, chain...))
+	log.SetFlags(0)
+	log.SetPrefix("")
+	return func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}
+}
+
+// DefaultLevel sets the level of the lines without a known prefix
+func (w *StdWriter) DefaultLevel(level logrus.Level) *StdWriter {
+	w.defaultLevel = level
+	return w
+}
+
+// Write logs every non-empty line of p
+func (w *StdWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(p, []byte{'\n'}) {
+		msg := strings.TrimSpace(string(line))
+		if msg == "" {
+			continue
+		}
+		level, msg := w.parseLevel(msg)
+		w.factory.NewBuilder(context.Background()).Name(w.chain...).Leaf().Log(level, msg)
+	}
+	return len(p), nil
+}
+
+// parseLevel finds the level from the prefix of msg, and strips the prefix
+func (w *StdWriter) parseLevel(msg string) (logrus.Level, string) {
+	body, bracket := msg, false
+	if strings.HasPrefix(body, "[") {
+		body, bracket = body[1:], true
+	}
+
+	for _, p := range levelPrefixes {
+		if len(body) < len(p.name) || !strings.EqualFold(body[:len(p.name)], p.name) {
+			continue
+		}
+		rest := body[len(p.name):]
+		switch {
+		case bracket && strings.HasPrefix(rest, "]"):
+			rest = rest[1:]
+		case !bracket && (strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, " ")):
+			rest = strings.TrimPrefix(rest, ":")
+		default:
+			continue
+		}
+		return p.level, strings.TrimSpace(rest)
+	}
+	return w.defaultLevel, msg
+}
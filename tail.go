@@ -0,0 +1,82 @@
+package wlog
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultTailLimit is the max number of entries held by a tail scope, the oldest are dropped first
+const defaultTailLimit = 1024
+
+// CtxKeyTail is the key to cache the tail buffering scope into a context
+var CtxKeyTail = struct{ CtxKeyTail struct{} }{}
+
+// tailScope holds the entries filtered out by level in a buffered branch,
+// until an entry at Error or higher occurs in the scope, or the scope is closed
+type tailScope struct {
+	mu     sync.Mutex
+	held   []*logrus.Entry
+	limit  int
+	failed bool
+	closed bool
+}
+
+// BufferedBranch works like Branch, and opens a tail buffering scope on the returned context
+// entries of the scope which are filtered out by level are held in memory, and written only when
+// an entry at Error or higher is logged in the same scope; closing the scope discards what is held
+// held entries reach the hooks of the logger only when they are written, the caller is still
+// computed for them when the logger reports callers
+func (b *Builder) BufferedBranch() (WLog, context.Context, func()) {
+	scope := &tailScope{limit: defaultTailLimit}
+	b.ctx = context.WithValue(b.ctx, CtxKeyTail, scope)
+	log, ctx := b.Branch()
+	return log, ctx, scope.close
+}
+
+// tailFromCtx get the tail scope from context
+func tailFromCtx(ctx context.Context) *tailScope {
+	if ctx == nil {
+		return nil
+	}
+	scope, _ := ctx.Value(CtxKeyTail).(*tailScope)
+	return scope
+}
+
+// hold keeps the entry in the scope, it returns true when the entry should be written
+// directly instead, which is the case once the scope has failed and before it is closed
+func (s *tailScope) hold(entry *logrus.Entry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	if s.failed {
+		return true
+	}
+	if len(s.held) >= s.limit {
+		s.held = append(s.held[:0], s.held[1:]...)
+	}
+	// the buffer of entry is recycled by logrus once written
+	held := *entry
+	held.Buffer = nil
+	s.held = append(s.held, &held)
+	return false
+}
+
+// fail marks the scope as failed, and returns the held entries to be written
+func (s *tailScope) fail() []*logrus.Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	held := s.held
+	s.held, s.failed = nil, true
+	return held
+}
+
+// close discards the held entries
+func (s *tailScope) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held, s.closed = nil, true
+}
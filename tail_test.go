@@ -0,0 +1,36 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBufferedBranch(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := createDiscardLogger()
+	logger.SetOutput(buf)
+
+	factory, err := NewFactory(logger)
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	ctx := context.Background()
+
+	_, okCtx, closeOK := factory.NewBuilder(ctx).Name("ok").BufferedBranch()
+	factory.NewBuilder(okCtx).Name("step").Leaf().Debug("ok debug")
+	closeOK()
+	if strings.Contains(buf.String(), "ok debug") {
+		t.Errorf("debug of a succeeded scope should be discarded, got %q", buf.String())
+	}
+
+	_, failCtx, closeFail := factory.NewBuilder(ctx).Name("fail").BufferedBranch()
+	factory.NewBuilder(failCtx).Name("step").Leaf().Debug("fail debug")
+	factory.NewBuilder(failCtx).Name("step").Leaf().Error("fail error")
+	closeFail()
+	out := buf.String()
+	if i, j := strings.Index(out, "fail debug"), strings.Index(out, "fail error"); i < 0 || j < i {
+		t.Errorf("debug of a failed scope should be flushed before the error, got %q", out)
+	}
+}
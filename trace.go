@@ -0,0 +1,146 @@
+package wlog
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand/v2"
+
+	"github.com/khicago/irr"
+	"github.com/sirupsen/logrus"
+)
+
+// CtxKeyTrace is the key to cache the trace context into a context
+var CtxKeyTrace = struct{ CtxKeyTrace struct{} }{}
+
+// ErrInvalidTraceparent is returned when a traceparent is not in the W3C format
+var ErrInvalidTraceparent = irr.Error("invalid traceparent")
+
+type (
+	// TraceID is the W3C trace id, shared by all the spans of a trace
+	TraceID [16]byte
+
+	// SpanID is the W3C span id (parent-id in traceparent)
+	SpanID [8]byte
+
+	// TraceContext identifies a span of a trace, as a W3C traceparent does
+	TraceContext struct {
+		TraceID TraceID
+		SpanID  SpanID
+		Flags   byte
+	}
+)
+
+// SetTracing enables or disables the trace_id and span_id of the Factory instance
+// once enabled, every branch creates a child span of the trace cached in context,
+// and a new trace is started when there is no trace in context or when detached
+func (f *Factory) SetTracing(enabled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tracing = enabled
+}
+
+// Tracing reports whether trace_id and span_id are enabled
+func (f *Factory) Tracing() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.tracing
+}
+
+// NewTrace starts a new trace, with a random trace id and span id
+func NewTrace() TraceContext {
+	tc := TraceContext{Flags: 1}
+	binary.BigEndian.PutUint64(tc.TraceID[:8], rand.Uint64())
+	binary.BigEndian.PutUint64(tc.TraceID[8:], rand.Uint64())
+	binary.BigEndian.PutUint64(tc.SpanID[:], rand.Uint64())
+	return tc
+}
+
+// ParseTraceparent parses a W3C traceparent, e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+func ParseTraceparent(traceparent string) (TraceContext, error) {
+	var tc TraceContext
+	if len(traceparent) < 55 || traceparent[2] != '-' || traceparent[35] != '-' || traceparent[52] != '-' {
+		return tc, ErrInvalidTraceparent
+	}
+	if traceparent[:2] == "ff" || (traceparent[:2] == "00" && len(traceparent) != 55) {
+		return tc, ErrInvalidTraceparent
+	}
+
+	var flags [1]byte
+	if _, err := hex.Decode(tc.TraceID[:], []byte(traceparent[3:35])); err != nil {
+		return tc, ErrInvalidTraceparent
+	}
+	if _, err := hex.Decode(tc.SpanID[:], []byte(traceparent[36:52])); err != nil {
+		return tc, ErrInvalidTraceparent
+	}
+	if _, err := hex.Decode(flags[:], []byte(traceparent[53:55])); err != nil {
+		return tc, ErrInvalidTraceparent
+	}
+	tc.Flags = flags[0]
+	if !tc.IsValid() {
+		return tc, ErrInvalidTraceparent
+	}
+	return tc, nil
+}
+
+// IsValid reports whether both the trace id and the span id are set
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != TraceID{} && tc.SpanID != SpanID{}
+}
+
+// Child creates a new span of the same trace, or starts a new trace when tc is not valid
+func (tc TraceContext) Child() TraceContext {
+	if !tc.IsValid() {
+		return NewTrace()
+	}
+	binary.BigEndian.PutUint64(tc.SpanID[:], rand.Uint64())
+	return tc
+}
+
+// Traceparent returns the W3C traceparent of the span
+func (tc TraceContext) Traceparent() string {
+	return "00-" + tc.TraceID.String() + "-" + tc.SpanID.String() + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+// WriteEntry write trace_id and span_id to entry
+func (tc TraceContext) WriteEntry(entry *logrus.Entry) *logrus.Entry {
+	return entry.WithFields(Fields{KeyTraceID: tc.TraceID.String(), KeySpanID: tc.SpanID.String()})
+}
+
+// WriteCtx cache trace context to context
+func (tc TraceContext) WriteCtx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, CtxKeyTrace, tc)
+}
+
+// TraceFromCtx get cached trace context from context
+func TraceFromCtx(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(CtxKeyTrace).(TraceContext)
+	return tc, ok
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// ---- private ----
+
+// traceFor returns the trace context of a new node made with the given strategy,
+// and whether the trace context should be cached into the new context
+func traceFor(ctx context.Context, strategy NodeStrategy) (TraceContext, bool) {
+	tc, _ := TraceFromCtx(ctx)
+	switch strategy {
+	case ForkBranch:
+		return tc.Child(), true
+	case NewTree:
+		return NewTrace(), true
+	default: // leaves log within the span in context
+		if !tc.IsValid() {
+			tc = NewTrace()
+		}
+		return tc, false
+	}
+}
@@ -0,0 +1,68 @@
+package wlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	factory, err := NewFactory(createDiscardLogger())
+	if err != nil {
+		t.Fatalf("create factory failed: %v", err)
+	}
+	factory.SetTracing(true)
+
+	incoming := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	parent, err := ParseTraceparent(incoming)
+	if err != nil {
+		t.Fatalf("parse traceparent failed: %v", err)
+	}
+	if parent.Traceparent() != incoming {
+		t.Errorf("traceparent should round trip, got %s", parent.Traceparent())
+	}
+
+	log, ctx := factory.NewBuilder(parent.WriteCtx(context.Background())).Name("branch").Branch()
+	child, ok := TraceFromCtx(ctx)
+	if !ok || child.TraceID != parent.TraceID || child.SpanID == parent.SpanID {
+		t.Errorf("branch should create a child span of the same trace, got %s", child.Traceparent())
+	}
+	if log.Data[KeyTraceID] != parent.TraceID.String() || log.Data[KeySpanID] != child.SpanID.String() {
+		t.Errorf("branch entry should carry the child span, got %v", log.Data)
+	}
+
+	leaf := factory.NewBuilder(ctx).Name("leaf").Leaf()
+	if leaf.Data[KeySpanID] != child.SpanID.String() {
+		t.Errorf("leaf should log within the span of context, got %v", leaf.Data)
+	}
+
+	if _, err = ParseTraceparent("00-00000000000000000000000000000000-00f067aa0ba902b7-01"); err == nil {
+		t.Error("zero trace id should be invalid")
+	}
+}
+
+type mapCarrier map[string]string
+
+func (c mapCarrier) Get(key string) string { return c[key] }
+func (c mapCarrier) Set(key, value string) { c[key] = value }
+
+func TestExtract(t *testing.T) {
+	carrier := mapCarrier{
+		HeaderChain:   "/" + strings.Repeat("a", maxExtractNodeLen+1) + strings.Repeat("/n", maxExtractNodes),
+		HeaderColumns: "tenant=t1&user_id=u1",
+	}
+
+	ctx := Extract(context.Background(), carrier)
+	if cols := ColumnsFromCtx(ctx); len(cols) != 0 {
+		t.Errorf("no column should be accepted without a whitelist, got %v", cols)
+	}
+
+	ctx = Extract(context.Background(), carrier, "tenant")
+	if cols := ColumnsFromCtx(ctx); len(cols) != 1 || cols[0].Key != "tenant" || cols[0].Value != "t1" {
+		t.Errorf("only whitelisted columns should be accepted, got %v", cols)
+	}
+	chain := ChainFromCtx(ctx)
+	if len(chain) != maxExtractNodes || len(chain[0]) != maxExtractNodeLen {
+		t.Errorf("the chain should be bounded, got %d nodes, first of %d bytes", len(chain), len(chain[0]))
+	}
+}
@@ -0,0 +1,145 @@
+package wlog
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// default options of TreeFormatter
+const (
+	defaultTreeTimestampFormat = "2006-01-02 15:04:05.000"
+	defaultTreeChainWidth      = 32
+	defaultTreeIndent          = "  "
+)
+
+// chainColors are the ANSI colors picked for top-level fingerprints
+var chainColors = []int{31, 32, 33, 34, 35, 36}
+
+// TreeFormatter is a logrus.Formatter for humans, which renders an entry as
+//
+//	<time> <LEVEL> <chain in a fixed column> <indent by chain depth><message> <sorted fields>
+//
+// the chain is colored by its top-level fingerprint, so that entries of a subtree are easy to scan
+type TreeFormatter struct {
+	// TimestampFormat is the format of the time, "2006-01-02 15:04:05.000" by default
+	TimestampFormat string
+	// ChainWidth is the width of the chain column, 32 by default, longer chains push the message
+	ChainWidth int
+	// Indent is written once per chain depth beyond the first, two spaces by default
+	Indent string
+	// DisableColors disables the ANSI colors
+	DisableColors bool
+}
+
+var _ logrus.Formatter = (*TreeFormatter)(nil)
+
+// Format implements logrus.Formatter
+func (tf *TreeFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b := entry.Buffer
+	if b == nil {
+		b = &bytes.Buffer{}
+	}
+
+	timestampFormat, chainWidth, indent := tf.TimestampFormat, tf.ChainWidth, tf.Indent
+	if timestampFormat == "" {
+		timestampFormat = defaultTreeTimestampFormat
+	}
+	if chainWidth <= 0 {
+		chainWidth = defaultTreeChainWidth
+	}
+	if indent == "" {
+		indent = defaultTreeIndent
+	}
+
+	chain, _ := ChainFromEntry(entry)
+
+	b.WriteString(entry.Time.Format(timestampFormat))
+	b.WriteByte(' ')
+	tf.colored(b, levelColor(entry.Level), fmt.Sprintf("%-5.5s", strings.ToUpper(entry.Level.String())))
+	b.WriteByte(' ')
+
+	path := chain.String()
+	tf.colored(b, chainColor(chain), path)
+	if pad := chainWidth - len(path); pad > 0 {
+		b.WriteString(strings.Repeat(" ", pad))
+	}
+	b.WriteByte(' ')
+
+	for i := 1; i < len(chain); i++ {
+		b.WriteString(indent)
+	}
+	b.WriteString(entry.Message)
+
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		if key != KeyFingerPrint && key != EntryKeyWLogSrc {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteByte(' ')
+		tf.colored(b, 90, key+"=")
+		writeTreeValue(b, entry.Data[key])
+	}
+
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// colored writes s in the ANSI color, unless colors are disabled
+func (tf *TreeFormatter) colored(b *bytes.Buffer, color int, s string) {
+	if tf.DisableColors || color == 0 {
+		b.WriteString(s)
+		return
+	}
+	fmt.Fprintf(b, "\x1b[%dm%s\x1b[0m", color, s)
+}
+
+// chainColor picks the color of the top-level fingerprint, the same fingerprint always gets the same color
+func chainColor(chain Chain) int {
+	if len(chain) == 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(chain[0]))
+	return chainColors[h.Sum32()%uint32(len(chainColors))]
+}
+
+func levelColor(level logrus.Level) int {
+	switch level {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return 37
+	case logrus.WarnLevel:
+		return 33
+	case logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel:
+		return 31
+	default:
+		return 36
+	}
+}
+
+// writeTreeValue writes the value of a field, quoted when it contains spaces or quotes
+func writeTreeValue(b *bytes.Buffer, value any) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case *TypedValue:
+		s = v.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		s = strconv.Quote(s)
+	}
+	b.WriteString(s)
+}
@@ -7,6 +7,24 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// syncOutput commits the output to stable storage, when it supports Sync
+func syncOutput(w io.Writer) {
+	if syncer, ok := w.(interface{ Sync() error }); ok {
+		_ = syncer.Sync()
+	}
+}
+
+// closeOutput closes the output, unless it is stdout or stderr, which are not owned by wlog
+func closeOutput(w io.Writer) error {
+	if w == os.Stdout || w == os.Stderr {
+		return nil
+	}
+	if closer, ok := w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
 func createTextLogger() *logrus.Logger {
 	logger := logrus.New()
 	logger.Formatter = &logrus.TextFormatter{
@@ -34,6 +52,16 @@ func createStderrLogger() *logrus.Logger {
 	return logger
 }
 
+func createFileLogger(out io.Writer) *logrus.Logger {
+	logger := logrus.New()
+	logger.Formatter = &logrus.TextFormatter{
+		DisableColors: true,
+		FullTimestamp: true,
+	}
+	logger.SetOutput(out)
+	return logger
+}
+
 func createDiscardLogger() *logrus.Logger {
 	logger := logrus.New()
 	logger.Formatter = &logrus.TextFormatter{
@@ -0,0 +1,182 @@
+package wlog
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// columnKind tags the type of the value kept unboxed in a Column
+type columnKind uint8
+
+const (
+	kindAny columnKind = iota // the value is Column.Value
+	kindInt
+	kindInt64
+	kindUint64
+	kindFloat64
+	kindString
+	kindBool
+	kindDuration
+	kindTime // num is the unix nano, and Value the location
+)
+
+// Typed columns keep their values unboxed, even in the fields of the entries, where they are referenced as *TypedValue
+// e.g. By(ctx, "auth").Columns(wlog.Int("user_id", id), wlog.Str("name", name))
+// when the logger has hooks, the values are boxed before the hooks fire, so that hooks see plain values
+
+// TypedValue is the value of a typed column in the fields of an entry, it refers to the column, so that it is not boxed
+// it prints as its value, Any returns the value
+type TypedValue Column
+
+// Int creates a column of an int
+func Int(key string, v int) Column {
+	return Column{Key: key, kind: kindInt, num: uint64(v)}
+}
+
+// Int64 creates a column of an int64
+func Int64(key string, v int64) Column {
+	return Column{Key: key, kind: kindInt64, num: uint64(v)}
+}
+
+// Uint64 creates a column of an uint64
+func Uint64(key string, v uint64) Column {
+	return Column{Key: key, kind: kindUint64, num: v}
+}
+
+// Float64 creates a column of a float64
+func Float64(key string, v float64) Column {
+	return Column{Key: key, kind: kindFloat64, num: math.Float64bits(v)}
+}
+
+// Str creates a column of a string
+func Str(key string, v string) Column {
+	return Column{Key: key, kind: kindString, str: v}
+}
+
+// Bool creates a column of a bool
+func Bool(key string, v bool) Column {
+	var num uint64
+	if v {
+		num = 1
+	}
+	return Column{Key: key, kind: kindBool, num: num}
+}
+
+// Dur creates a column of a duration
+func Dur(key string, v time.Duration) Column {
+	return Column{Key: key, kind: kindDuration, num: uint64(v)}
+}
+
+// Time creates a column of a time, the monotonic clock reading is stripped
+// times out of the range of unix nano are kept boxed
+func Time(key string, v time.Time) Column {
+	if v.IsZero() || v.Year() < 1678 || v.Year() > 2261 {
+		return Column{Key: key, Value: v}
+	}
+	// a location is a pointer, which is boxed without allocation
+	return Column{Key: key, kind: kindTime, num: uint64(v.UnixNano()), Value: v.Location()}
+}
+
+// Err creates a column of an error, keyed by logrus.ErrorKey as WithError does
+func Err(err error) Column {
+	return Column{Key: logrus.ErrorKey, Value: err}
+}
+
+// Any creates a column of any value, as Builder.Field does
+func Any(key string, v any) Column {
+	return Column{Key: key, Value: v}
+}
+
+// Any returns the value of the column, boxing the typed value
+func (col Column) Any() any {
+	switch col.kind {
+	case kindInt:
+		return int(col.num)
+	case kindInt64:
+		return int64(col.num)
+	case kindUint64:
+		return col.num
+	case kindFloat64:
+		return math.Float64frombits(col.num)
+	case kindString:
+		return col.str
+	case kindBool:
+		return col.num == 1
+	case kindDuration:
+		return time.Duration(col.num)
+	case kindTime:
+		return time.Unix(0, int64(col.num)).In(col.Value.(*time.Location))
+	default:
+		return col.Value
+	}
+}
+
+// Any returns the value
+func (v *TypedValue) Any() any {
+	return Column(*v).Any()
+}
+
+// String formats the value as fmt.Sprint does
+func (v *TypedValue) String() string {
+	switch v.kind {
+	case kindInt, kindInt64:
+		return strconv.FormatInt(int64(v.num), 10)
+	case kindUint64:
+		return strconv.FormatUint(v.num, 10)
+	case kindFloat64:
+		return strconv.FormatFloat(math.Float64frombits(v.num), 'g', -1, 64)
+	case kindString:
+		return v.str
+	case kindBool:
+		return strconv.FormatBool(v.num == 1)
+	case kindDuration:
+		return time.Duration(v.num).String()
+	default:
+		return fmt.Sprint(v.Any())
+	}
+}
+
+// MarshalJSON writes the value as JSONFormatter does
+func (v *TypedValue) MarshalJSON() ([]byte, error) {
+	return v.appendJSON(nil)
+}
+
+// appendJSON appends the JSON encoding of the value to buf
+func (v *TypedValue) appendJSON(buf []byte) ([]byte, error) {
+	switch v.kind {
+	case kindInt, kindInt64:
+		return strconv.AppendInt(buf, int64(v.num), 10), nil
+	case kindUint64:
+		return strconv.AppendUint(buf, v.num, 10), nil
+	case kindFloat64:
+		return appendJSONFloat(buf, math.Float64frombits(v.num), 64), nil
+	case kindString:
+		return appendJSONString(buf, v.str), nil
+	case kindBool:
+		return strconv.AppendBool(buf, v.num == 1), nil
+	default:
+		return appendJSONValue(buf, v.Any())
+	}
+}
+
+// boxFields replaces the typed values of the fields by their boxed values
+func boxFields(fields logrus.Fields) {
+	for key, v := range fields {
+		if typed, ok := v.(*TypedValue); ok {
+			fields[key] = typed.Any()
+		}
+	}
+}
+
+// box boxes the typed values in place, so that the columns cached in a branch are boxed once for all its entries
+func (c Columns) box() {
+	for i, col := range c {
+		if col.kind != kindAny {
+			c[i] = Column{Key: col.Key, Value: col.Any()}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package wlogtest provides an in-memory recording Factory, and assertions on what was logged
+package wlogtest
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/khicago/wlog"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// Entry is a recorded log entry
+	Entry struct {
+		Time    time.Time
+		Level   logrus.Level
+		Message string
+		Chain   wlog.Chain
+		// Columns are the fields of the entry except the chain, sorted by key
+		Columns wlog.Columns
+	}
+
+	// Recorder is a logrus.Hook recording every entry fired on its logger
+	Recorder struct {
+		mu      sync.Mutex
+		entries []Entry
+		factory *wlog.Factory
+	}
+)
+
+var _ logrus.Hook = (*Recorder)(nil)
+
+// New creates a Recorder, whose Factory logs at Trace level to nothing but the recorder
+func New() *Recorder {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.TraceLevel)
+
+	r := &Recorder{}
+	logger.AddHook(r)
+	r.factory, _ = wlog.NewFactory(logger)
+	return r
+}
+
+// Factory returns the recording Factory, it is nil when the Recorder is only used as a hook
+func (r *Recorder) Factory() *wlog.Factory {
+	return r.factory
+}
+
+// Levels implements logrus.Hook, all the levels are recorded
+func (r *Recorder) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (r *Recorder) Fire(entry *logrus.Entry) error {
+	chain, _ := wlog.ChainFromEntry(entry)
+	columns := make(wlog.Columns, 0, len(entry.Data))
+	for key, value := range entry.Data {
+		if key == wlog.KeyFingerPrint {
+			continue
+		}
+		columns = append(columns, wlog.Column{Key: key, Value: value})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, Entry{
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+		Chain:   chain,
+		Columns: columns.Sorted(),
+	})
+	return nil
+}
+
+// Entries returns a copy of the recorded entries
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// Reset drops the recorded entries
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// Find returns the entries at the level, under the chain, whose message contains substr
+// an empty chain matches any chain
+func (r *Recorder) Find(level logrus.Level, chain string, substr string) []Entry {
+	var found []Entry
+	for _, e := range r.Entries() {
+		if e.Level == level && (chain == "" || e.Chain.String() == chain) && strings.Contains(e.Message, substr) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// AssertLogged fails the test when no entry at the level, under the chain, contains substr in its message
+func (r *Recorder) AssertLogged(t testing.TB, level logrus.Level, chain string, substr string) {
+	t.Helper()
+	if len(r.Find(level, chain, substr)) == 0 {
+		t.Errorf("no %s entry under %q containing %q, recorded:\n%s", level, chain, substr, r.dump())
+	}
+}
+
+// AssertNotLogged fails the test when any entry at the level, under the chain, contains substr in its message
+func (r *Recorder) AssertNotLogged(t testing.TB, level logrus.Level, chain string, substr string) {
+	t.Helper()
+	if found := r.Find(level, chain, substr); len(found) > 0 {
+		t.Errorf("unexpected %s entry under %q containing %q: %q", level, chain, substr, found[0].Message)
+	}
+}
+
+// AssertNoErrors fails the test when any entry at Error level or higher was recorded
+func (r *Recorder) AssertNoErrors(t testing.TB) {
+	t.Helper()
+	for _, e := range r.Entries() {
+		if e.Level <= logrus.ErrorLevel {
+			t.Errorf("unexpected %s entry under %s: %q", e.Level, e.Chain, e.Message)
+		}
+	}
+}
+
+// dump prints the recorded entries for failure messages
+func (r *Recorder) dump() string {
+	var builder strings.Builder
+	for _, e := range r.Entries() {
+		builder.WriteString("  ")
+		builder.WriteString(e.Level.String())
+		builder.WriteString(" ")
+		builder.WriteString(e.Chain.String())
+		builder.WriteString(" ")
+		builder.WriteString(e.Message)
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
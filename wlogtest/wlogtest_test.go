@@ -0,0 +1,51 @@
+package wlogtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := New()
+	_, ctx := rec.Factory().NewBuilder(context.Background()).Name("auth").Branch()
+	rec.Factory().NewBuilder(ctx).Name("login").Field("user_id", 42).Leaf().Debug("user logged in")
+
+	rec.AssertLogged(t, logrus.DebugLevel, "/auth/login", "logged in")
+	rec.AssertNotLogged(t, logrus.InfoLevel, "/auth/login", "logged in")
+	rec.AssertNoErrors(t)
+
+	entries := rec.Find(logrus.DebugLevel, "/auth/login", "")
+	if len(entries) != 1 {
+		t.Fatalf("expect 1 entry, got %d", len(entries))
+	}
+	var userID any
+	for _, col := range entries[0].Columns {
+		if col.Key == "user_id" {
+			userID = col.Value
+		}
+	}
+	if userID != 42 {
+		t.Errorf("columns should be recorded, got %v", entries[0].Columns)
+	}
+}
+
+func TestRecorderBufferedBranch(t *testing.T) {
+	rec := New()
+	rec.Factory().SetLevel(logrus.InfoLevel)
+	ctx := context.Background()
+
+	_, okCtx, closeOK := rec.Factory().NewBuilder(ctx).Name("ok").BufferedBranch()
+	rec.Factory().NewBuilder(okCtx).Name("step").Leaf().Debug("ok debug")
+	closeOK()
+	rec.AssertNotLogged(t, logrus.DebugLevel, "/ok/step", "ok debug")
+
+	_, failCtx, closeFail := rec.Factory().NewBuilder(ctx).Name("fail").BufferedBranch()
+	rec.Factory().NewBuilder(failCtx).Name("step").Leaf().Debug("fail debug")
+	rec.AssertNotLogged(t, logrus.DebugLevel, "/fail/step", "fail debug")
+	rec.Factory().NewBuilder(failCtx).Name("step").Leaf().Error("fail error")
+	closeFail()
+	rec.AssertLogged(t, logrus.DebugLevel, "/fail/step", "fail debug")
+	rec.AssertLogged(t, logrus.ErrorLevel, "/fail/step", "fail error")
+}